@@ -0,0 +1,481 @@
+// This file contains the optional control API of the client (see Config.APIAddr).
+// Calls can be placed and the state queried on every elevator; cancelling orders and putting an elevator in or out
+// of service are handled by the master, the other elevators answer with an error telling which one it is
+package elevator
+
+import (
+	"Driver-go/elevio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+var errOrderNotFound = errors.New("No order has this id")
+
+type apiCall struct { // Body of POST /api/calls
+	Floor  int
+	Button string // up, down or cab
+}
+
+type apiDestination struct { // Body of POST /api/destinations, and its answer
+	Floor       int
+	Destination int
+	Elevator    int // Answer: the car to take
+}
+
+type apiService struct { // Body of POST /api/service
+	Elevator  int
+	InService bool
+}
+
+type apiFire struct { // Body of POST /api/fire, and its answer
+	Active      bool
+	RecallFloor int
+}
+
+type apiFirefighter struct { // Body of POST /api/firefighter
+	Active bool
+}
+
+type apiDoor struct { // Body of POST /api/door
+	Held bool
+}
+
+type apiIndependent struct { // Body of POST /api/independent
+	Active bool
+}
+
+type apiInspection struct { // Body of POST /api/inspection
+	Command string // on, off, up, down or stop
+}
+
+type apiTraffic struct { // Body of POST /api/traffic (Mode only), and the answer of GET and POST /api/traffic
+	Mode       string       // normal, upPeak or downPeak. POST: also auto, back to the schedule and the detection
+	Source     string       // detected, schedule or api
+	LobbyFloor int          // The lobby of this elevator
+	Rates      [][2]float64 // The hall calls per minute at each floor (up, down). Only known by the master
+}
+
+// Sets the elevators that are out of service
+func (c *Client) setOutOfService(ids []int) {
+	c.mutex_outOfService.Lock()
+	defer c.mutex_outOfService.Unlock()
+	c.outOfService = make(map[int]bool)
+	for _, id := range ids {
+		c.outOfService[id] = true
+	}
+}
+
+// Returns the elevators that are out of service, sorted
+func (c *Client) outOfServiceList() []int {
+	c.mutex_outOfService.Lock()
+	defer c.mutex_outOfService.Unlock()
+	ids := []int{}
+	for id := range c.outOfService {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+func (c *Client) isOutOfService(id int) bool {
+	c.mutex_outOfService.Lock()
+	defer c.mutex_outOfService.Unlock()
+	return c.outOfService[id]
+}
+
+// Forgets the order with this id (for the master). Returns the message that makes the elevators drop it,
+// nil if no elevator has it yet
+func cancelOrder(hallOrders map[orderKey]hallOrderRecord, allStates [numElev]ElevState, id string) (*CancelOrderMsg, error) {
+	for key, record := range hallOrders {
+		if record.Order.Id == id {
+			delete(hallOrders, key) // Its lights are turned off with the next HallLightsMsg
+			if record.Status != assigned {
+				return nil, nil
+			}
+			return &CancelOrderMsg{Id: -1, Order: record.Order}, nil
+		}
+	}
+
+	for elevator, state := range allStates {
+		for _, order := range state.LocalRequests {
+			if order.Id == id && order.OrderType == cab {
+				return &CancelOrderMsg{Id: elevator, Order: order}, nil
+			}
+		}
+	}
+	return nil, errOrderNotFound
+}
+
+// Drops the orders cancelled by the master
+func (c *Client) handleCancelOrder() {
+	for {
+		var a CancelOrderMsg
+		select {
+		case a = <-c.cancelOrderRx:
+		case <-c.ctx.Done():
+			return
+		}
+		if a.Id != c.id && a.Id != -1 {
+			continue
+		}
+
+		lockMutexes(&c.mutex_elevatorOrders, &c.mutex_d, &c.mutex_posArray)
+		remaining := []Order{}
+		for _, order := range c.elevatorOrders {
+			if !order.SameButton(a.Order) {
+				remaining = append(remaining, order)
+			}
+		}
+		if len(remaining) == len(c.elevatorOrders) { // We do not have it
+			unlockMutexes(&c.mutex_elevatorOrders, &c.mutex_d, &c.mutex_posArray)
+			continue
+		}
+		c.elevatorOrders = remaining
+
+		// Update & send the new state of the elevator to the master
+		c.updateState(c.lastFloor)
+		c.singleStateTx <- StateMsg{c.id, c.latestState}
+		unlockMutexes(&c.mutex_elevatorOrders, &c.mutex_d, &c.mutex_posArray)
+
+		c.turnOffCabLights(a.Order)
+		c.turnOffHallLights(a.Order)
+		c.logEvent(logFSM, "Order %s (floor %d) was cancelled", a.Order.Id, a.Order.Floor)
+
+		if len(remaining) > 0 { // Go to the next order instead
+			c.drv_newOrder <- remaining[0]
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"Error": err.Error()})
+}
+
+// Hands a command to the master routine and waits for its answer. Returns false (and answers the request)
+// if we are not the master or it did not answer in time
+func (c *Client) runMasterCommand(w http.ResponseWriter, cmd masterCommand) bool {
+	if c.Role() != "Master" {
+		master := -1
+		c.mutex_peers.Lock()
+		for _, peer := range c.peers {
+			if peer.Role == "Master" {
+				master = peer.Id
+			}
+		}
+		c.mutex_peers.Unlock()
+		writeJSON(w, http.StatusConflict, map[string]interface{}{"Error": "This elevator is not the master", "Master": master})
+		return false
+	}
+
+	cmd.reply = make(chan error, 1)
+	select {
+	case c.masterCommands <- cmd:
+	case <-time.After(apiTimeout):
+		writeError(w, http.StatusServiceUnavailable, errors.New("The master did not take the command"))
+		return false
+	}
+
+	select {
+	case err := <-cmd.reply:
+		if err == errOrderNotFound {
+			writeError(w, http.StatusNotFound, err)
+			return false
+		} else if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return false
+		}
+	case <-time.After(apiTimeout):
+		writeError(w, http.StatusServiceUnavailable, errors.New("The master did not answer"))
+		return false
+	}
+	return true
+}
+
+// GET /api/state: the view of the cluster from this elevator (the same as the dashboard)
+func (c *Client) handleAPIState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("Use GET"))
+		return
+	}
+	writeJSON(w, http.StatusOK, c.dashboardSnapshot())
+}
+
+// POST /api/calls {"Floor": 2, "Button": "up"}: presses a button of this elevator
+func (c *Client) handleAPICall(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("Use POST"))
+		return
+	}
+	var call apiCall
+	if err := json.NewDecoder(r.Body).Decode(&call); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	buttons := map[string]elevio.ButtonType{"up": elevio.BT_HallUp, "down": elevio.BT_HallDown, "cab": elevio.BT_Cab}
+	button, ok := buttons[strings.ToLower(call.Button)]
+	switch {
+	case !ok:
+		writeError(w, http.StatusBadRequest, errors.New("Button must be up, down or cab"))
+		return
+	case call.Floor < 0 || call.Floor >= numFloors,
+		call.Floor == numFloors-1 && button == elevio.BT_HallUp,
+		call.Floor == 0 && button == elevio.BT_HallDown:
+		writeError(w, http.StatusBadRequest, errors.New("There is no such button"))
+		return
+	case button == elevio.BT_Cab && !c.servesFloor(call.Floor):
+		writeError(w, http.StatusBadRequest, fmt.Errorf("The car does not serve floor %d", call.Floor))
+		return
+	}
+
+	// The press takes the same path as the ones of the panel
+	select {
+	case c.drv_buttons <- elevio.ButtonEvent{Floor: call.Floor, Button: button}:
+		writeJSON(w, http.StatusAccepted, call)
+	case <-time.After(apiTimeout):
+		writeError(w, http.StatusServiceUnavailable, errors.New("The button press was not taken"))
+	}
+}
+
+// POST /api/destinations {"Floor": 0, "Destination": 3}: enters a destination at the hall panel of this elevator
+// (destination dispatch), answers with the car to take
+func (c *Client) handleAPIDestination(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("Use POST"))
+		return
+	}
+	var destination apiDestination
+	if err := json.NewDecoder(r.Body).Decode(&destination); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	switch {
+	case !c.destinationDispatch:
+		writeError(w, http.StatusConflict, errors.New("Destination dispatch is off on this elevator"))
+		return
+	case destination.Floor < 0 || destination.Floor >= numFloors || destination.Destination < 0 || destination.Destination >= numFloors:
+		writeError(w, http.StatusBadRequest, errors.New("There is no such floor"))
+		return
+	case destination.Floor == destination.Destination:
+		writeError(w, http.StatusBadRequest, errors.New("The destination is the floor of the hall"))
+		return
+	case c.inFireService(): // Like the hall buttons (see handleButtonPress)
+		writeError(w, http.StatusConflict, errors.New("The destinations are not taken during the fire service"))
+		return
+	}
+
+	// The master answers the call with its id
+	call := c.destinationCall(destination.Floor, destination.Destination)
+	car := c.waitForDestinationCar(call.Id)
+	defer c.stopWaitingForDestinationCar(call.Id)
+	c.enterDestination(call)
+	select {
+	case destination.Elevator = <-car:
+		if destination.Elevator == -1 {
+			writeError(w, http.StatusConflict, errors.New("No car can take this destination"))
+			return
+		}
+		writeJSON(w, http.StatusOK, destination)
+	case <-time.After(apiTimeout):
+		writeError(w, http.StatusServiceUnavailable, errors.New("No car was assigned in time"))
+	}
+}
+
+// DELETE /api/orders/<id>: cancels an order (master only)
+func (c *Client) handleAPICancelOrder(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("Use DELETE"))
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/api/orders/")
+	if id == "" {
+		writeError(w, http.StatusBadRequest, errors.New("The id of the order is missing"))
+		return
+	}
+
+	if c.runMasterCommand(w, masterCommand{Kind: "cancel", OrderId: id}) {
+		writeJSON(w, http.StatusOK, map[string]string{"Cancelled": id})
+	}
+}
+
+// POST /api/service {"Elevator": 1, "InService": false}: puts an elevator in or out of service (master only)
+func (c *Client) handleAPIService(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("Use POST"))
+		return
+	}
+	var service apiService
+	if err := json.NewDecoder(r.Body).Decode(&service); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if service.Elevator < 0 || service.Elevator >= numElev {
+		writeError(w, http.StatusBadRequest, errors.New("There is no such elevator"))
+		return
+	}
+
+	if c.runMasterCommand(w, masterCommand{Kind: "service", Elevator: service.Elevator, InService: service.InService}) {
+		writeJSON(w, http.StatusOK, service)
+	}
+}
+
+// POST /api/fire {"Active": true}: starts (or resets) the fire recall of the building, from any elevator
+func (c *Client) handleAPIFire(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("Use POST"))
+		return
+	}
+	var fire apiFire
+	if err := json.NewDecoder(r.Body).Decode(&fire); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := c.requestFireRecall(fire.Active); err != nil {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	}
+	fire.Active, fire.RecallFloor = c.fireRecallState()
+	writeJSON(w, http.StatusOK, fire)
+}
+
+// POST /api/firefighter {"Active": true}: the firefighter key switch of this elevator (once it is recalled)
+func (c *Client) handleAPIFirefighter(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("Use POST"))
+		return
+	}
+	var firefighter apiFirefighter
+	if err := json.NewDecoder(r.Body).Decode(&firefighter); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := c.setFirefighter(firefighter.Active); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"Mode": c.currentMode()})
+}
+
+// POST /api/door {"Held": true}: holds (or releases) the door button of this elevator, in firefighter operation
+func (c *Client) handleAPIDoor(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("Use POST"))
+		return
+	}
+	var door apiDoor
+	if err := json.NewDecoder(r.Body).Decode(&door); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := c.pressDoorButton(door.Held); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, door)
+}
+
+// POST /api/independent {"Active": true}: puts this elevator in independent service (or back to normal)
+func (c *Client) handleAPIIndependent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("Use POST"))
+		return
+	}
+	var independent apiIndependent
+	if err := json.NewDecoder(r.Body).Decode(&independent); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := c.setIndependentService(independent.Active); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"Mode": c.currentMode()})
+}
+
+// The traffic mode of the building, as known by this elevator
+func (c *Client) trafficSnapshot() apiTraffic {
+	c.mutex_traffic.Lock()
+	defer c.mutex_traffic.Unlock()
+	traffic := apiTraffic{Mode: c.trafficMode, Source: c.trafficSource, LobbyFloor: c.lobbyFloor}
+	if c.Role() == "Master" {
+		traffic.Rates = c.trafficRates
+	}
+	return traffic
+}
+
+// GET /api/traffic: the traffic mode of the building.
+// POST /api/traffic {"Mode": "upPeak"}: forces a traffic mode (master only), auto to stop forcing it
+func (c *Client) handleAPITraffic(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, c.trafficSnapshot())
+		return
+	case http.MethodPost:
+	default:
+		writeError(w, http.StatusMethodNotAllowed, errors.New("Use GET or POST"))
+		return
+	}
+	var traffic apiTraffic
+	if err := json.NewDecoder(r.Body).Decode(&traffic); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	forced := traffic.Mode
+	switch traffic.Mode {
+	case trafficNormal, trafficUpPeak, trafficDownPeak:
+	case "auto":
+		forced = ""
+	default:
+		writeError(w, http.StatusBadRequest, errors.New("Mode must be normal, upPeak, downPeak or auto"))
+		return
+	}
+
+	if c.runMasterCommand(w, masterCommand{Kind: "traffic", Mode: forced}) {
+		writeJSON(w, http.StatusOK, c.trafficSnapshot())
+	}
+}
+
+// POST /api/inspection {"Command": "up"}: a command of the inspection mode of this elevator (on, off, up, down or
+// stop). Answers with the state of the elevator
+func (c *Client) handleAPIInspection(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("Use POST"))
+		return
+	}
+	var inspection apiInspection
+	if err := json.NewDecoder(r.Body).Decode(&inspection); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	switch strings.ToLower(inspection.Command) {
+	case "on", "off", "up", "down", "stop":
+	default:
+		writeError(w, http.StatusBadRequest, errors.New("Command must be on, off, up, down or stop"))
+		return
+	}
+
+	if err := c.inspect(strings.ToLower(inspection.Command)); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	c.mutex_state.Lock()
+	state := c.latestState
+	c.mutex_state.Unlock()
+	writeJSON(w, http.StatusOK, state)
+}
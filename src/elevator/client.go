@@ -0,0 +1,778 @@
+// Package elevator implements the client of a single elevator of the multiple elevators system.
+// Each Client owns its state, so several clients can run in the same process.
+package elevator
+
+import (
+	"Driver-go/elevio"
+	"Network-go/network/logging"
+	"Network-go/network/peers"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Config contains the parameters of a client
+type Config struct {
+	Id   int    // The id of the elevator: a positive integer, unique, consecutive, starting at 0
+	Role string // The initial role of the elevator: Regular, Master or PrimaryBackup
+
+	// When master, a hall order not served within this multiple of its estimated service time is re-assigned
+	// to another elevator. 0 means the default (3)
+	HallOrderTimeoutFactor float64
+
+	// The address on which the Prometheus metrics are served (e.g. ":9100"). Empty for no endpoint
+	MetricsAddr string
+
+	// The address on which the live dashboard is served (e.g. ":8080"). Empty for no dashboard.
+	// It can be the same as MetricsAddr
+	DashboardAddr string
+
+	// The address on which the control API is served (e.g. ":8081"). Empty for no API.
+	// It can be the same as MetricsAddr or DashboardAddr
+	APIAddr string
+
+	// The levels and the format of the logs (info in text by default). They are shared by the whole process
+	Log logging.Config
+
+	// The floor the cars are sent to by the fire recall (Phase I), when we are master. 0 by default
+	FireRecallFloor int
+
+	// The floor where the up-peak starts and the down-peak ends (see traffic.go). 0 by default
+	LobbyFloor int
+
+	// The periods during which a traffic mode is used when we are master, in local time, e.g.
+	// "07:30-09:30=upPeak,16:30-18:00=downPeak". Out of them, the mode is detected from the hall calls
+	TrafficSchedule string
+
+	// Where the idle cars wait in normal traffic when we are master: none (where they stopped), lobby, zones
+	// (spread over the floors) or demand (the floors with the most hall calls). Empty means none
+	ParkingPolicy string
+
+	// How long a car stays idle before it is parked. 0 means the default (20 s)
+	ParkingDelay time.Duration
+
+	// The floors this car stops at, e.g. "0,2-3" for an express car skipping floor 1 (see zoning.go). Empty for
+	// all of them
+	ServedFloors string
+
+	// Destination dispatch: the passengers enter their destination at our hall panel (see destinationDispatch.go)
+	// and the up and down buttons are ignored. Off by default
+	DestinationDispatch bool
+}
+
+func (cfg Config) validate() error {
+	// If role is different that Regular, Master or PrimaryBackup, cancel the program
+	if cfg.Role != "Regular" && cfg.Role != "Master" && cfg.Role != "PrimaryBackup" {
+		return errors.New("Role must be either Regular, Master or PrimaryBackup")
+	}
+
+	// If the ID is not valid, cancel the program
+	if cfg.Id < 0 || cfg.Id >= numElev {
+		return errors.New("ID must be a positive integer smaller than the number of elevators")
+	}
+
+	if cfg.HallOrderTimeoutFactor < 0 {
+		return errors.New("The hall order timeout factor must be positive")
+	}
+
+	if cfg.FireRecallFloor < 0 || cfg.FireRecallFloor >= numFloors {
+		return errors.New("The fire recall floor must be one of the floors")
+	}
+
+	if cfg.LobbyFloor < 0 || cfg.LobbyFloor >= numFloors {
+		return errors.New("The lobby floor must be one of the floors")
+	}
+
+	if _, err := parseTrafficSchedule(cfg.TrafficSchedule); err != nil {
+		return err
+	}
+
+	if !validParkingPolicy(cfg.ParkingPolicy) {
+		return errors.New("The parking policy must be none, lobby, zones or demand")
+	}
+
+	if cfg.ParkingDelay < 0 {
+		return errors.New("The parking delay must be positive")
+	}
+
+	if _, err := parseServedFloors(cfg.ServedFloors); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Client is a single elevator: its driver, its connection to the other elevators and its local state
+type Client struct {
+	id        int
+	driver    Driver
+	transport Transport
+
+	hallOrderTimeoutFactor float64 // See Config
+
+	ctx    context.Context // Cancelled by Stop
+	cancel context.CancelFunc
+
+	roleCtx       context.Context // Cancelled when the role changes, it stops the routines of the previous role
+	roleCancel    context.CancelFunc
+	mutex_roleCtx sync.Mutex
+
+	// Section_START -- STATE
+	role       string // The role of the elevator (Master, Regular or PrimaryBackup)
+	mutex_role sync.Mutex
+
+	elevatorOrders       []Order // The local orders array for this elevator
+	mutex_elevatorOrders sync.Mutex
+
+	posArray       [2*numFloors - 1]bool // The position array for this elevator
+	mutex_posArray sync.Mutex
+
+	ableToCloseDoors bool // A boolean that tells us if we are able to close the doors
+	mutex_doors      sync.Mutex
+
+	lastFloor int // The last floor the elevator was at
+
+	latestState ElevState // The latest state of the elevator
+	mutex_state sync.Mutex
+
+	activeElevators       []int // The active elevators
+	mutex_activeElevators sync.Mutex
+
+	backupStates      [numElev]ElevState // The backup states array
+	pendingHallOrders []Order            // The hall orders waiting for a confirmation (from the master), also guarded by mutex_backup
+	mutex_backup      sync.Mutex
+
+	isWaiting     bool
+	mutex_waiting sync.Mutex
+
+	d                 elevio.MotorDirection // The current direction of the elevator
+	leavingInspection bool                  // Inspection turned off between floors, it ends at the next floor (also guarded by mutex_d)
+	mutex_d           sync.Mutex
+
+	lastDirForStopFunction elevio.MotorDirection // The last direction the elevator was moving in before the stop button was pressed
+
+	mutex_lastSeenMotorStop       sync.Mutex // Mutex for the lastSeen variable in detectMotorStop
+	mutex_elevatorOrdersMotorStop sync.Mutex
+
+	peers       []peers.ElevIdentity // The elevators on the network (from the latest peer update)
+	mutex_peers sync.Mutex
+
+	shuttingDown       bool // Set by Shutdown, the elevator does not take new orders anymore
+	mutex_shuttingDown sync.Mutex
+
+	isolated       bool    // Set when we lose all the other elevators, until we rejoin the cluster
+	servedOffline  []Order // The hall orders served while isolated, reported to the master when we rejoin
+	mutex_isolated sync.Mutex
+
+	rejoinCancel context.CancelFunc // Stops the rejoin in progress, nil if there is none
+	mutex_rejoin sync.Mutex
+
+	hallOrderReassignments int // The number of hall orders re-assigned by the watchdog while we were master
+	mutex_reassignments    sync.Mutex
+
+	orderStats *orderStatistics // The wait and journey times of the orders served while we were master
+
+	// Metrics (see metrics.go)
+	metricsAddr         string
+	hallOrdersPending   int       // Hall orders not assigned yet, while we are master
+	hallOrdersAssigned  int       // Hall orders assigned, while we are master
+	hallOrdersCompleted int       // Hall orders served while we were master
+	motorStopDetections int       // Elevators detected as stopped while we were master
+	obstructedSince     time.Time // Zero when the door is not obstructed
+	obstructedFor       time.Duration
+	mutex_metrics       sync.Mutex
+
+	// Dashboard (see dashboard.go)
+	dashboardAddr             string
+	confirmedHallOrders       []Order // The hall orders lit by the master, from its latest HallLightsMsg
+	mutex_confirmedHallOrders sync.Mutex
+	events                    []dashboardEvent // The latest events
+	eventSeq                  int              // The sequence number of the latest event
+	mutex_events              sync.Mutex
+
+	// Control API (see api.go)
+	apiAddr            string
+	outOfService       map[int]bool // The elevators put out of service, from the master
+	mutex_outOfService sync.Mutex
+
+	// Fire service (see fireService.go)
+	fireRecallFloor int    // See Config
+	mode            string // The mode of the car: normal, fireRecall, firefighter, independent or inspection
+	fireRecall      bool   // The fire recall of the building, from the master
+	recallFloor     int    // The recall floor of the master
+	doorHeld        bool   // The door button is held (firefighter operation)
+	mutex_mode      sync.Mutex
+
+	// Traffic modes (see traffic.go)
+	lobbyFloor      int             // See Config
+	trafficSchedule []trafficPeriod // See Config
+	trafficMode     string          // The traffic mode of the building, from the master
+	trafficSource   string          // detected, schedule or api
+	trafficForced   string          // The traffic mode forced through the API, empty if none
+	trafficRates    [][2]float64    // The hall calls per minute at each floor (up, down), computed while we are master
+	mutex_traffic   sync.Mutex
+
+	// Parking of the idle cars (see parking.go)
+	parkingPolicy string        // See Config
+	parkingDelay  time.Duration // See Config
+
+	servedFloors []int // See Config, nil for every floor (see zoning.go)
+
+	// Destination dispatch (see destinationDispatch.go)
+	destinationDispatch bool                // See Config
+	destinationWaiters  map[string]chan int // The destination calls entered at our hall (by id) waiting for a car
+	mutex_destination   sync.Mutex
+
+	startedAt      time.Time // Makes the ids of our orders unique across restarts
+	orderSequence  int       // The number of orders created by this elevator
+	mutex_orderIds sync.Mutex
+	// Section_END -- STATE
+
+	// Section_START -- CHANNELS
+	roleChannel  chan string           // Broadcast role
+	peerUpdateCh chan peers.PeerUpdate // Updates from peers
+	peerTxEnable chan bool             // Enables/disables the transmitter
+
+	// Channels for the driver
+	drv_buttons                  chan elevio.ButtonEvent
+	drv_floors                   chan int
+	drv_floors2                  chan int
+	drv_obstr                    chan bool
+	drv_stop                     chan bool
+	drv_newOrder                 chan Order
+	drv_DirectionChange          chan elevio.MotorDirection
+	drv_buttons_forCabLights     chan elevio.ButtonEvent
+	drv_buttons_forOrderHandling chan elevio.ButtonEvent
+	localStatesForCabOrders      chan StateMsg // ALL - Turn off cab lights after completing order
+	selfUpdate                   chan StateMsg // ALL - Check for updates of the state to prevent loosing the elevator
+
+	// Channels for the network
+	hallBtnTx                  chan Order        // ALL - Send hall orders to the master
+	hallOrderRx                chan HallOrderMsg // ALL - Receive hall orders from the master
+	singleStateTx              chan StateMsg     // ALL - Send the state of the elevator to the master
+	hallOrderCompletedLightsRx chan []Order      // ALL - Confirm hall order (for lights)
+	activeElevatorsChannelTx   chan []int        // ALL - The channel on which we send the active elevators list
+	activeElevatorsChannelRx   chan []int        // ALL - The channel on which we receive the active elevators list
+	retrieveCabOrdersRx        chan CabOrderMsg  // ALL - Retrieve the cab orders from the master
+	askForCabOrdersTx          chan int          // ALL - Ask for the cab orders from the master
+
+	allStatesFromMasterRx  chan [numElev]ElevState // ALL - Receive all states from the master
+	singleStateFromSlaveTx chan StateMsg           // ALL - Send the state of the elevator to the master
+
+	leaveTx    chan LeaveMsg    // ALL - Announce that we are shutting down
+	leaveRx    chan LeaveMsg    // ALL - Receive the shutdown of another elevator
+	leaveAckTx chan LeaveAckMsg // ALL - Acknowledge the shutdown of another elevator
+	leaveAckRx chan LeaveAckMsg // ALL - Receive the acknowledgements of a shutdown
+	leaveAcks  chan int         // LOCAL - The ids of the elevators that acknowledged our shutdown
+
+	hallLightsRx    chan HallLightsMsg    // ALL - Receive the confirmed hall orders from the master
+	hallOrdersAckTx chan HallOrdersAckMsg // ALL - Acknowledge the hall orders we know of
+
+	rejoinTx    chan RejoinMsg    // ALL - Announce that we are back after a network partition
+	rejoinAckRx chan RejoinAckMsg // ALL - Receive the role given by the master when we rejoin
+
+	orderServedTx         chan OrderServedMsg         // ALL - Tell the master when our orders were served
+	cancelOrderRx         chan CancelOrderMsg         // ALL - Receive the orders cancelled by the master
+	fireRecallTx          chan FireRecallMsg          // ALL - Turn the fire recall on or off
+	inspectionRx          chan InspectionMsg          // ALL - Receive the inspection commands (elevctl inspect)
+	inspectionTx          chan InspectionAckMsg       // ALL - Answer the inspection commands
+	parkRx                chan ParkMsg                // ALL - Receive the floor where we wait while idle
+	destinationCallTx     chan DestinationCallMsg     // ALL - Send the destinations entered at our hall to the master
+	destinationAssignedRx chan DestinationAssignedMsg // ALL - Receive the cars taking the destinations
+	masterCommands        chan masterCommand          // LOCAL - The commands of the API, handled by the master routine
+
+	// Channels for specific roles
+	hallBtnRx             chan Order                  // MASTER - Receive hall orders from slaves
+	hallOrderTx           chan HallOrderMsg           // MASTER - Send hall orders to slaves
+	singleStateRx         chan StateMsg               // MASTER - Receive states from slaves
+	backupStatesRx        chan [numElev]ElevState     // BACKUP - Receive all states from master
+	backupStatesTx        chan [numElev]ElevState     // MASTER - Send all states to backup
+	hallOrderCompletedTx  chan []Order                // Master - Send completed hallorder(s) to single elevators
+	retrieveCabOrdersTx   chan CabOrderMsg            // ALL - Retrieve the cab orders from the master
+	askForCabOrdersRx     chan int                    // ALL - Ask for the cab orders from the master
+	rejoinRx              chan RejoinMsg              // MASTER - Receive the elevators that come back after a network partition
+	rejoinAckTx           chan RejoinAckMsg           // MASTER - Give their role to the elevators that rejoin
+	hallLightsTx          chan HallLightsMsg          // MASTER - Broadcast the confirmed hall orders
+	hallOrdersAckRx       chan HallOrdersAckMsg       // MASTER - Receive the hall orders known by the slaves
+	orderServedRx         chan OrderServedMsg         // MASTER - Receive the served orders, for the statistics
+	cancelOrderTx         chan CancelOrderMsg         // MASTER - Cancel orders
+	fireRecallRx          chan FireRecallMsg          // MASTER - Receive the fire recall switches
+	parkTx                chan ParkMsg                // MASTER - Send the idle cars to their floor
+	destinationCallRx     chan DestinationCallMsg     // MASTER - Receive the destinations entered at the halls
+	destinationAssignedTx chan DestinationAssignedMsg // MASTER - Tell the hall panels which car takes them
+
+	allStatesFromMasterTx  chan [numElev]ElevState // ALL - Send all states to the master
+	singleStateFromSlaveRx chan StateMsg           // ALL - Receive the state of the elevator from the master
+	// Section_END -- CHANNELS
+}
+
+// New creates a client for the elevator driven by driver, talking to the others through transport
+func New(cfg Config, driver Driver, transport Transport) (*Client, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+	if driver == nil || transport == nil {
+		return nil, errors.New("A driver and a transport are required")
+	}
+	if err := logging.Configure(cfg.Log); err != nil {
+		return nil, err
+	}
+
+	if cfg.HallOrderTimeoutFactor == 0 {
+		cfg.HallOrderTimeoutFactor = defaultHallOrderTimeoutFactor
+	}
+	trafficSchedule, _ := parseTrafficSchedule(cfg.TrafficSchedule) // Checked by validate
+	servedFloors, _ := parseServedFloors(cfg.ServedFloors)
+	if cfg.ParkingPolicy == "" {
+		cfg.ParkingPolicy = parkingNone
+	}
+	if cfg.ParkingDelay == 0 {
+		cfg.ParkingDelay = defaultParkingDelay
+	}
+
+	c := &Client{
+		id:        cfg.Id,
+		role:      cfg.Role,
+		driver:    driver,
+		transport: transport,
+
+		hallOrderTimeoutFactor: cfg.HallOrderTimeoutFactor,
+		startedAt:              time.Now(),
+		orderStats:             newOrderStatistics(),
+		metricsAddr:            cfg.MetricsAddr,
+		dashboardAddr:          cfg.DashboardAddr,
+		apiAddr:                cfg.APIAddr,
+		outOfService:           make(map[int]bool),
+		fireRecallFloor:        cfg.FireRecallFloor,
+		mode:                   modeNormal,
+		latestState:            ElevState{Mode: modeNormal, ServedFloors: servedFloors},
+		lobbyFloor:             cfg.LobbyFloor,
+		trafficSchedule:        trafficSchedule,
+		trafficMode:            trafficNormal,
+		trafficSource:          "detected",
+		parkingPolicy:          cfg.ParkingPolicy,
+		parkingDelay:           cfg.ParkingDelay,
+		servedFloors:           servedFloors,
+		destinationDispatch:    cfg.DestinationDispatch,
+		destinationWaiters:     make(map[string]chan int),
+
+		roleChannel:  make(chan string),
+		peerUpdateCh: make(chan peers.PeerUpdate),
+		peerTxEnable: make(chan bool),
+
+		drv_buttons:                  make(chan elevio.ButtonEvent, 100),
+		drv_floors:                   make(chan int),
+		drv_floors2:                  make(chan int),
+		drv_obstr:                    make(chan bool),
+		drv_stop:                     make(chan bool),
+		drv_newOrder:                 make(chan Order),
+		drv_DirectionChange:          make(chan elevio.MotorDirection),
+		drv_buttons_forCabLights:     make(chan elevio.ButtonEvent, 100),
+		drv_buttons_forOrderHandling: make(chan elevio.ButtonEvent, 100),
+		localStatesForCabOrders:      make(chan StateMsg),
+		selfUpdate:                   make(chan StateMsg),
+
+		hallBtnTx:                  make(chan Order),
+		hallOrderRx:                make(chan HallOrderMsg),
+		singleStateTx:              make(chan StateMsg),
+		hallOrderCompletedLightsRx: make(chan []Order),
+		activeElevatorsChannelTx:   make(chan []int),
+		activeElevatorsChannelRx:   make(chan []int),
+		retrieveCabOrdersRx:        make(chan CabOrderMsg),
+		askForCabOrdersTx:          make(chan int),
+		allStatesFromMasterRx:      make(chan [numElev]ElevState),
+		singleStateFromSlaveTx:     make(chan StateMsg),
+		leaveTx:                    make(chan LeaveMsg),
+		leaveRx:                    make(chan LeaveMsg),
+		leaveAckTx:                 make(chan LeaveAckMsg),
+		leaveAckRx:                 make(chan LeaveAckMsg),
+		leaveAcks:                  make(chan int, numElev),
+		hallLightsRx:               make(chan HallLightsMsg),
+		hallOrdersAckTx:            make(chan HallOrdersAckMsg),
+		rejoinTx:                   make(chan RejoinMsg),
+		rejoinAckRx:                make(chan RejoinAckMsg),
+		orderServedTx:              make(chan OrderServedMsg),
+		cancelOrderRx:              make(chan CancelOrderMsg),
+		fireRecallTx:               make(chan FireRecallMsg),
+		inspectionRx:               make(chan InspectionMsg),
+		inspectionTx:               make(chan InspectionAckMsg),
+		parkRx:                     make(chan ParkMsg),
+		destinationCallTx:          make(chan DestinationCallMsg),
+		destinationAssignedRx:      make(chan DestinationAssignedMsg),
+		masterCommands:             make(chan masterCommand),
+
+		hallBtnRx:              make(chan Order),
+		hallOrderTx:            make(chan HallOrderMsg),
+		singleStateRx:          make(chan StateMsg),
+		backupStatesRx:         make(chan [numElev]ElevState),
+		backupStatesTx:         make(chan [numElev]ElevState),
+		hallOrderCompletedTx:   make(chan []Order),
+		retrieveCabOrdersTx:    make(chan CabOrderMsg),
+		askForCabOrdersRx:      make(chan int),
+		allStatesFromMasterTx:  make(chan [numElev]ElevState),
+		singleStateFromSlaveRx: make(chan StateMsg),
+		rejoinRx:               make(chan RejoinMsg),
+		rejoinAckTx:            make(chan RejoinAckMsg),
+		hallLightsTx:           make(chan HallLightsMsg),
+		hallOrdersAckRx:        make(chan HallOrdersAckMsg),
+		orderServedRx:          make(chan OrderServedMsg),
+		cancelOrderTx:          make(chan CancelOrderMsg),
+		fireRecallRx:           make(chan FireRecallMsg),
+		parkTx:                 make(chan ParkMsg),
+		destinationCallRx:      make(chan DestinationCallMsg),
+		destinationAssignedTx:  make(chan DestinationAssignedMsg),
+	}
+
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.roleCtx, c.roleCancel = context.WithCancel(c.ctx)
+
+	return c, nil
+}
+
+// Id returns the id of the elevator
+func (c *Client) Id() int {
+	return c.id
+}
+
+// Role returns the current role of the elevator
+func (c *Client) Role() string {
+	c.mutex_role.Lock()
+	defer c.mutex_role.Unlock()
+	return c.role
+}
+
+// Returns the logger of a component, its lines carry our id and our role
+func (c *Client) logger(component string) logging.Logger {
+	return logging.New(component).WithIdentity(func() (int, string) { return c.id, c.Role() })
+}
+
+func (c *Client) setRole(newRole string) {
+	c.mutex_role.Lock()
+	c.role = newRole
+	c.mutex_role.Unlock()
+}
+
+// Stops the routines of the previous role (and their sockets), then starts the ones of newRole.
+// A new master starts from the backupStates and re-assigns lostOrders itself once it is running.
+// A Regular elevator has no role routine
+func (c *Client) startRoleRoutines(newRole string, lostOrders []Order) {
+	c.mutex_roleCtx.Lock()
+	c.roleCancel()
+	c.roleCtx, c.roleCancel = context.WithCancel(c.ctx)
+	ctx := c.roleCtx
+	c.mutex_roleCtx.Unlock()
+
+	switch newRole {
+	case "Master":
+		c.mutex_backup.Lock()
+		allStates := c.backupStates
+		c.mutex_backup.Unlock()
+
+		go c.masterRoutine(ctx, allStates, lostOrders)
+	case "PrimaryBackup":
+		go c.primaryBackupRoutine(ctx)
+	}
+}
+
+// Run starts the elevator and blocks until ctx is cancelled or Stop is called
+func (c *Client) Run(ctx context.Context) error {
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.Stop()
+		case <-c.ctx.Done():
+		}
+	}()
+
+	id := c.id
+
+	// Section_START -- NETWORK INITIALIZATION
+	go c.transport.PeerTransmitter(c.ctx, PeerChannel_PORT, id, c.roleChannel, c.peerTxEnable) // Broadcast role
+	c.roleChannel <- c.Role()
+	go c.transport.PeerReceiver(c.ctx, PeerChannel_PORT, c.peerUpdateCh) // Listen for updates
+
+	c.serveHTTP() // The optional endpoints (metrics, dashboard, API)
+	// Section_END -- NETWORK INITIALIZATION
+
+	// Section_START -- CHANNELS
+	go relayDrvButtons(c.drv_buttons, c.drv_buttons_forCabLights, c.drv_buttons_forOrderHandling)
+
+	go c.driver.PollButtons(c.drv_buttons)         // Button updates
+	go c.driver.PollFloorSensor(c.drv_floors)      // Floors updates
+	go c.driver.PollFloorSensor2(c.drv_floors2)    // Floors updates (for tracking position)
+	go c.driver.PollObstructionSwitch(c.drv_obstr) // Obstruction updates
+	go c.driver.PollStopButton(c.drv_stop)         // Stop button presses
+
+	go c.transport.Receiver(c.ctx, HallOrder_PORT, c.hallOrderRx)
+	go c.transport.Transmitter(c.ctx, HallOrderRawBTN_PORT, c.hallBtnTx)
+	go c.transport.Transmitter(c.ctx, SingleElevatorState_PORT, c.singleStateTx)
+	go c.transport.Receiver(c.ctx, HallOrderCompleted_PORT, c.hallOrderCompletedLightsRx)
+	go c.transport.Receiver(c.ctx, ActiveElevators_PORT, c.activeElevatorsChannelRx)
+	go c.transport.Transmitter(c.ctx, ActiveElevators_PORT, c.activeElevatorsChannelTx)
+	go c.transport.Receiver(c.ctx, RetrieveCabOrders_PORT, c.retrieveCabOrdersRx)
+	go c.transport.Transmitter(c.ctx, AskForCabOrders_PORT, c.askForCabOrdersTx)
+	go c.transport.Receiver(c.ctx, SpamFromMaster_PORT, c.allStatesFromMasterRx)
+	go c.transport.Transmitter(c.ctx, SpamFromSlave_PORT, c.singleStateFromSlaveTx)
+	go c.transport.Transmitter(c.ctx, Leave_PORT, c.leaveTx, c.leaveAckTx)
+	go c.transport.Receiver(c.ctx, Leave_PORT, c.leaveRx, c.leaveAckRx)
+	go c.transport.Receiver(c.ctx, HallLights_PORT, c.hallLightsRx)
+	go c.transport.Transmitter(c.ctx, HallLights_PORT, c.hallOrdersAckTx)
+	go c.transport.Transmitter(c.ctx, Rejoin_PORT, c.rejoinTx)
+	go c.transport.Receiver(c.ctx, Rejoin_PORT, c.rejoinAckRx)
+	go c.transport.Transmitter(c.ctx, OrderServed_PORT, c.orderServedTx)
+	go c.transport.Receiver(c.ctx, CancelOrder_PORT, c.cancelOrderRx)
+	go c.transport.Transmitter(c.ctx, FireRecall_PORT, c.fireRecallTx)
+	go c.transport.Receiver(c.ctx, Inspection_PORT, c.inspectionRx)
+	go c.transport.Transmitter(c.ctx, Inspection_PORT, c.inspectionTx)
+	go c.transport.Receiver(c.ctx, Park_PORT, c.parkRx)
+	go c.transport.Transmitter(c.ctx, DestinationCall_PORT, c.destinationCallTx)
+	go c.transport.Receiver(c.ctx, DestinationAssigned_PORT, c.destinationAssignedRx)
+
+	go forwarderStateMsg(c.singleStateTx, c.selfUpdate)
+
+	// Section_END -- CHANNELS
+
+	c.askForCabOrdersTx <- id // Ask for the cab orders from the master
+
+	// Section_START -- ROLES-SPECIFIC ACTIONS
+	if c.Role() == "Master" {
+		c.activeElevators = append(c.activeElevators, id) // Add the master to the activeElevators list
+
+		// This is the initial states of the elevators
+		c.backupStates = initAllStates(c.backupStates)
+	}
+
+	// Starting the Master or PrimaryBackup Routine
+	c.startRoleRoutines(c.Role(), nil)
+	// Section_END -- ROLES-SPECIFIC ACTIONS
+
+	// Section_START -- LOCAL INITIALIZATION
+	c.d = elevio.MD_Down // Setting the initial direction of the elevator
+
+	// Initialize the elevator - going to ground floor
+	c.initSingleElev(c.d)
+
+	consumer1drv_floors := make(chan int) // Consumers for the drv_floors (relay)
+	consumer2drv_floors := make(chan int)
+	consumer3drv_floors := make(chan int)
+	go relayDrvFloors(c.drv_floors, consumer1drv_floors, consumer2drv_floors, consumer3drv_floors)
+
+	c.d = elevio.MD_Stop // Update d so that states are accurate
+
+	// Send the initial state of the elevator to the master
+	c.singleStateTx <- StateMsg{id, c.latestState}
+
+	// Starting the goroutines for tracking the position of the elevator & attending to specific orders
+	go c.trackPosition() // Starts tracking the position of the elevator
+	go c.attendToSpecificOrder(consumer2drv_floors)
+
+	// Section_START -- RERTIEVE CAB ORDERS
+	// We send our ID to the master to ask for the cab orders
+	c.askForCabOrdersTx <- id
+	// Secton_END -- RETRIEVE CAB ORDERS
+
+	c.updateState(c.lastFloor)
+	c.singleStateTx <- StateMsg{id, c.latestState}
+
+	// Section_END -- LOCAL INITIALIZATION
+
+	go c.handleFloorLights(consumer3drv_floors)
+	go c.handleObstruction()                        // Listens to the obstruction button
+	go c.handleElevatorUpdate()                     // Listens to active elevators updates
+	go c.handleButtonPress()                        // Listens to new button presses
+	go c.handleNewFloorReached(consumer1drv_floors) // Listens to floor updates
+	go c.handleNewHallOrder()                       // Listens to new orders from the master
+	go c.handlePeerUpdate()                         // Listens to peer updates on the network
+	go c.handleTurnOffLightsHallOrderCompleted()    // Listens for completed hall orders
+	go c.handleHallLights()                         // Listens for the confirmed hall orders
+	go c.handleCancelOrder()                        // Listens for the orders cancelled by the master
+	go c.handleTurnOffLightsCabOrderCompleted()
+	go c.handleTurnOnLightsCabOrder()
+	go c.handleRetrieveCab() // Listens for cab order retrieving
+	go c.handleStopButton()  // Listens for stop button presses
+	if recallSwitch, ok := c.driver.(FireRecallSwitch); ok {
+		go c.handleFireRecallSwitch(recallSwitch) // Listens to the fire recall switch, if the car has one
+	}
+	if panel, ok := c.driver.(FirefighterPanel); ok {
+		go c.handleFirefighterPanel(panel) // Listens to the firefighter key switch and door button, if the car has them
+	}
+	go c.handleInspectionCommands() // Listens to the inspection commands of elevctl
+	go c.handleParkRequests()       // Listens to the floors where the master wants us to wait
+	go c.handleDestinationAssignments()
+	if serviceSwitch, ok := c.driver.(IndependentServiceSwitch); ok {
+		go c.handleIndependentServiceSwitch(serviceSwitch) // Listens to the independent service switch, if the car has one
+	}
+
+	go c.receiveSpamFromMaster()
+	go c.spamMaster() // Sends the state of the elevator to the master periodically
+
+	<-c.ctx.Done()
+	return nil
+}
+
+// Stop stops the elevator and all the routines of the client
+func (c *Client) Stop() {
+	c.cancel()
+	c.driver.SetMotorDirection(elevio.MD_Stop)
+}
+
+// HallOrderReassignments returns the number of hall orders that were not served in time and re-assigned to
+// another elevator while this elevator was master (for diagnostics)
+func (c *Client) HallOrderReassignments() int {
+	c.mutex_reassignments.Lock()
+	defer c.mutex_reassignments.Unlock()
+	return c.hallOrderReassignments
+}
+
+func (c *Client) isShuttingDown() bool {
+	c.mutex_shuttingDown.Lock()
+	defer c.mutex_shuttingDown.Unlock()
+	return c.shuttingDown
+}
+
+func (c *Client) isIsolated() bool {
+	c.mutex_isolated.Lock()
+	defer c.mutex_isolated.Unlock()
+	return c.isolated
+}
+
+// Marks us as isolated from the other elevators, or as back in the cluster (which forgets the orders served offline)
+func (c *Client) setIsolated(isolated bool) {
+	c.mutex_isolated.Lock()
+	c.isolated = isolated
+	if !isolated {
+		c.servedOffline = nil
+	}
+	c.mutex_isolated.Unlock()
+}
+
+func (c *Client) isRejoining() bool {
+	c.mutex_rejoin.Lock()
+	defer c.mutex_rejoin.Unlock()
+	return c.rejoinCancel != nil
+}
+
+// Stops the rejoin in progress, if any
+func (c *Client) stopRejoin() {
+	c.mutex_rejoin.Lock()
+	if c.rejoinCancel != nil {
+		c.rejoinCancel()
+		c.rejoinCancel = nil
+	}
+	c.mutex_rejoin.Unlock()
+}
+
+// Shutdown stops the elevator gracefully before calling Stop: the car stops at the next floor and opens its door,
+// its hall orders are handed back to the master and its role is taken over by the other elevators.
+// If ctx expires before the other elevators acknowledge it, the client is stopped anyway
+func (c *Client) Shutdown(ctx context.Context) error {
+	c.mutex_shuttingDown.Lock()
+	c.shuttingDown = true
+	c.mutex_shuttingDown.Unlock()
+
+	defer c.Stop()
+	id := c.id
+
+	// Section_START -- STOP AT THE NEXT FLOOR
+	for {
+		c.mutex_posArray.Lock()
+		currentPos := c.extractPos()
+		c.mutex_posArray.Unlock()
+
+		if currentPos == float32(int(currentPos)) { // We are at a floor
+			break
+		}
+
+		select {
+		case <-time.After(30 * time.Millisecond):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	c.mutex_d.Lock()
+	c.d = elevio.MD_Stop
+	c.driver.SetMotorDirection(c.d)
+	c.mutex_d.Unlock()
+
+	c.setDoorOpen(true) // Let the passengers out
+	// Section_END -- STOP AT THE NEXT FLOOR
+
+	// Section_START -- HAND OVER
+	// Only keep the cab orders, so that they can be retrieved when we come back
+	c.mutex_elevatorOrders.Lock()
+	hallOrders := extractHallOrders(c.elevatorOrders)
+	cabOrders := []Order{}
+	for _, order := range c.elevatorOrders {
+		if order.OrderType == cab {
+			cabOrders = append(cabOrders, order)
+		}
+	}
+	c.elevatorOrders = cabOrders
+	c.updateState(c.lastFloor)
+	c.mutex_elevatorOrders.Unlock()
+
+	// Announce our shutdown with a final snapshot of the states
+	leave := LeaveMsg{Id: id, Role: c.Role(), HallOrders: hallOrders}
+	c.mutex_backup.Lock()
+	leave.States = c.backupStates
+	c.mutex_backup.Unlock()
+	c.mutex_state.Lock()
+	leave.States[id] = c.latestState
+	c.mutex_state.Unlock()
+
+	// Wait for all the other elevators to acknowledge it
+	waiting := make(map[int]bool)
+	c.mutex_peers.Lock()
+	for _, peer := range c.peers {
+		if peer.Id != id {
+			waiting[peer.Id] = true
+		}
+	}
+	c.mutex_peers.Unlock()
+
+	var err error
+waitForAcks:
+	for len(waiting) > 0 {
+		select { // The transmitter is gone if the client was already stopped
+		case c.leaveTx <- leave:
+		case <-ctx.Done():
+			err = ctx.Err()
+			break waitForAcks
+		case <-c.ctx.Done():
+			err = c.ctx.Err()
+			break waitForAcks
+		}
+
+		timeout := time.After(resendRateLeave)
+		for {
+			select {
+			case from := <-c.leaveAcks:
+				delete(waiting, from)
+				if len(waiting) == 0 {
+					break waitForAcks
+				}
+			case <-timeout:
+				continue waitForAcks
+			case <-ctx.Done():
+				err = ctx.Err()
+				break waitForAcks
+			}
+		}
+	}
+
+	c.startRoleRoutines("Regular", nil) // Our role has been taken over
+	c.setRole("Regular")
+	// Section_END -- HAND OVER
+
+	select { // Leave the network
+	case c.peerTxEnable <- false:
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-c.ctx.Done():
+	}
+
+	c.logger(logClient).Infof("Shutdown finished")
+	return err
+}
@@ -0,0 +1,1054 @@
+package elevator
+
+import (
+	"Network-go/network/bcast"
+	"Network-go/network/peers"
+	"context"
+	"math"
+	"time"
+)
+
+// UDPTransport is the default Transport: it broadcasts on the local network using the Network-go module.
+// The zero value does not count its messages, see NewUDPTransport
+type UDPTransport struct {
+	counters *bcast.Counters
+}
+
+// NewUDPTransport returns a UDPTransport that counts its messages on each port (for the metrics)
+func NewUDPTransport() UDPTransport {
+	return UDPTransport{counters: bcast.NewCounters()}
+}
+
+func (t UDPTransport) Transmitter(ctx context.Context, port int, chans ...interface{}) {
+	bcast.CountingTransmitter(ctx, t.counters, port, chans...)
+}
+
+func (t UDPTransport) Receiver(ctx context.Context, port int, chans ...interface{}) {
+	bcast.CountingReceiver(ctx, t.counters, port, chans...)
+}
+
+func (UDPTransport) PeerTransmitter(ctx context.Context, port int, id int, roleChan <-chan string, transmitEnable <-chan bool) {
+	peers.Transmitter(ctx, port, id, roleChan, transmitEnable)
+}
+
+func (UDPTransport) PeerReceiver(ctx context.Context, port int, peerUpdateCh chan<- peers.PeerUpdate) {
+	peers.Receiver(ctx, port, peerUpdateCh)
+}
+
+func (c *Client) spamMaster() {
+	// Send the state of the elevator to the master periodically
+	for {
+		select {
+		case <-time.After(30 * time.Millisecond):
+		case <-c.ctx.Done():
+			return
+		}
+		c.mutex_state.Lock()
+		c.singleStateFromSlaveTx <- StateMsg{
+			Id:    c.id,
+			State: c.latestState,
+		}
+		c.mutex_state.Unlock()
+	}
+}
+
+func (c *Client) spamSlaves(ctx context.Context) {
+	// Send the state of the elevator to the slaves periodically
+	for {
+		select {
+		case <-time.After(30 * time.Millisecond):
+		case <-ctx.Done():
+			return
+		}
+		c.mutex_backup.Lock()
+		allStates := c.backupStates
+		c.mutex_backup.Unlock()
+
+		select {
+		case c.allStatesFromMasterTx <- allStates:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func extractHallOrders(orders []Order) []Order {
+	var hallOrders []Order
+	for _, order := range orders {
+		if order.OrderType == hall { // Check if it's a HallOrder
+			hallOrders = append(hallOrders, order) // Add to the hallOrders slice
+		}
+	}
+	return hallOrders
+}
+
+// Tells whether a hall order received by the master is one of our buttons: the messages come from the network
+func validHallOrder(order Order) bool {
+	switch {
+	case order.OrderType != hall || order.Floor < 0 || order.Floor >= numFloors:
+		return false
+	case order.Direction == up:
+		return order.Floor < numFloors-1
+	case order.Direction == down:
+		return order.Floor > 0
+	}
+	return false
+}
+
+// Tells whether an elevator id received from the network is one of ours, before it indexes the states
+func validElevatorId(id int) bool {
+	return id >= 0 && id < numElev
+}
+
+func (c *Client) redistributeOrders(localRequest []Order) {
+	// Re-assign the hall orders, i.e. send them again to the master
+	for _, order := range localRequest {
+		if order.OrderType == hall {
+			c.hallBtnTx <- order // It keeps its id and history
+		}
+	}
+}
+
+func (c *Client) detectMotorStop(ctx context.Context, newElevatorActivity chan elevatorActivity, idCompletedHallOrderForTimer chan int) {
+
+	var lastSeen = make(map[int]time.Time)                 // A map for keeping track of the times since we last saw the elevators
+	var elevatorOrdersMotorStop = make([][]Order, numElev) // The last received local request to one of the elevators
+
+	var handledPowerLoss = false
+	var hasPowerLoss = false
+	_ = hasPowerLoss
+
+	for i, _ := range lastSeen {
+		lastSeen[i] = time.Now()
+	}
+
+	go func() {
+		for ctx.Err() == nil {
+			// Timer for all the elevators
+			lockMutexes(&c.mutex_lastSeenMotorStop)
+
+			for id, t := range lastSeen {
+				if time.Since(t) > timerHallOrder && len(elevatorOrdersMotorStop[id]) > 0 && !handledPowerLoss {
+
+					hasPowerLoss = true
+
+					// Signal that the elevator with the corresponding id is inactive
+					c.mutex_activeElevators.Lock()
+					alreadyExists := c.isElevatorActive(id)
+
+					if alreadyExists {
+						c.removeElevator(id) // Remove the elevator from the activeElevators list
+					}
+
+					c.activeElevators = sortElevators(c.activeElevators)
+					c.mutex_activeElevators.Unlock()
+
+					c.activeElevatorsChannelTx <- c.activeElevators // Send the activeElevators list to the other elevators
+
+					// Send hallOrders to hallBtnTx
+
+					lockMutexes(&c.mutex_elevatorOrdersMotorStop)
+					c.redistributeOrders(elevatorOrdersMotorStop[id])
+					unlockMutexes(&c.mutex_elevatorOrdersMotorStop)
+
+					handledPowerLoss = true // Set the flag to true to avoid multiple signals
+
+					c.mutex_metrics.Lock()
+					c.motorStopDetections++
+					c.mutex_metrics.Unlock()
+				}
+			}
+			unlockMutexes(&c.mutex_lastSeenMotorStop)
+			time.Sleep(pollRateMotorStop)
+		}
+	}()
+
+	go func() {
+		for {
+			// Register new elevatorActivity
+			var a elevatorActivity
+			select {
+			case a = <-newElevatorActivity:
+			case <-ctx.Done():
+				return
+			}
+			id := a.id
+			timestamp := a.timestamp
+
+			lockMutexes(&c.mutex_lastSeenMotorStop)
+			lastSeen[id] = timestamp
+			unlockMutexes(&c.mutex_lastSeenMotorStop)
+
+			lockMutexes(&c.mutex_elevatorOrdersMotorStop)
+			elevatorOrdersMotorStop[id] = a.localRequests
+			unlockMutexes(&c.mutex_elevatorOrdersMotorStop)
+		}
+	}()
+
+	for {
+		// We have receive confirmation that the hallOrder was attended to
+		var id int
+		select {
+		case id = <-idCompletedHallOrderForTimer:
+		case <-ctx.Done():
+			return
+		}
+		lockMutexes(&c.mutex_lastSeenMotorStop)
+		lastSeen[id] = time.Time{}
+		unlockMutexes(&c.mutex_lastSeenMotorStop)
+
+		if hasPowerLoss { // We have a state update AND a power loss -> we don't have a power loss anymore
+			hasPowerLoss = false
+			handledPowerLoss = false // Reset the flag
+
+			// Add the elevator back to the activeElevators list
+			c.mutex_activeElevators.Lock()
+			alreadyExists := c.isElevatorActive(id)
+			if !alreadyExists {
+				c.activeElevators = append(c.activeElevators, id)
+			}
+			c.activeElevators = sortElevators(c.activeElevators)
+			c.mutex_activeElevators.Unlock()
+
+			c.activeElevatorsChannelTx <- c.activeElevators // Send the activeElevators list to the other elevators
+		}
+	}
+
+}
+
+// Function to find elements in oldStateOrders that are not in newStateOrders and vice versa
+func findUniqueOrders(oldOrders, newOrders []Order) []Order {
+	// Use maps to track orders
+	oldOrdersMap := make(map[orderKey]Order)
+	newOrdersMap := make(map[orderKey]Order)
+	var uniqueOrders []Order
+
+	// Add all old orders to oldOrdersMap
+	for _, order := range oldOrders {
+		oldOrdersMap[order.key()] = order
+	}
+
+	// Add all new orders to newOrdersMap
+	for _, order := range newOrders {
+		newOrdersMap[order.key()] = order
+	}
+
+	// Find orders in oldOrdersMap that are not in newOrdersMap
+	for key, order := range oldOrdersMap {
+		if _, exists := newOrdersMap[key]; !exists {
+			uniqueOrders = append(uniqueOrders, order)
+		}
+	}
+
+	// Find orders in newOrdersMap that are not in oldOrdersMap
+	for key, order := range newOrdersMap {
+		if _, exists := oldOrdersMap[key]; !exists {
+			uniqueOrders = append(uniqueOrders, order)
+		}
+	}
+
+	return uniqueOrders
+}
+
+func (c *Client) masterRoutine(ctx context.Context, allStates [numElev]ElevState, lostOrders []Order) {
+	c.logger(logMaster).Infof("Master routine started, %d lost orders to re-assign", len(lostOrders))
+
+	go c.transport.Receiver(ctx, HallOrderRawBTN_PORT, c.hallBtnRx)
+	go c.transport.Receiver(ctx, SingleElevatorState_PORT, c.singleStateRx)
+	go c.transport.Transmitter(ctx, HallOrder_PORT, c.hallOrderTx)
+	go c.transport.Transmitter(ctx, AllStates_PORT, c.backupStatesTx)
+	go c.transport.Transmitter(ctx, HallOrderCompleted_PORT, c.hallOrderCompletedTx)
+	go c.transport.Transmitter(ctx, RetrieveCabOrders_PORT, c.retrieveCabOrdersTx)
+	go c.transport.Receiver(ctx, AskForCabOrders_PORT, c.askForCabOrdersRx)
+	go c.transport.Transmitter(ctx, SpamFromMaster_PORT, c.allStatesFromMasterTx)
+	go c.transport.Receiver(ctx, SpamFromSlave_PORT, c.singleStateFromSlaveRx)
+	go c.transport.Receiver(ctx, Rejoin_PORT, c.rejoinRx)
+	go c.transport.Transmitter(ctx, HallLights_PORT, c.hallLightsTx)
+	go c.transport.Receiver(ctx, HallLights_PORT, c.hallOrdersAckRx)
+	go c.transport.Transmitter(ctx, Rejoin_PORT, c.rejoinAckTx)
+	go c.transport.Receiver(ctx, OrderServed_PORT, c.orderServedRx)
+	go c.transport.Transmitter(ctx, CancelOrder_PORT, c.cancelOrderTx)
+	go c.transport.Receiver(ctx, FireRecall_PORT, c.fireRecallRx)
+	go c.transport.Transmitter(ctx, Park_PORT, c.parkTx)
+	go c.transport.Receiver(ctx, DestinationCall_PORT, c.destinationCallRx)
+	go c.transport.Transmitter(ctx, DestinationAssigned_PORT, c.destinationAssignedTx)
+
+	// allStates is the array of elevator states for continously monitoring the elevators
+	// It will be updated whenever we receive a new state from the slaves
+
+	newElevatorActivity := make(chan elevatorActivity) // Functionality for the motor stop
+	idCompletedHallOrderForTimer := make(chan int)
+	go c.detectMotorStop(ctx, newElevatorActivity, idCompletedHallOrderForTimer)
+
+	c.mutex_backup.Lock()
+	c.backupStates = allStates
+	c.mutex_backup.Unlock()
+
+	// Re-assign the orders of the elevators we took over from, as if their buttons had been pressed again
+	go func() {
+		for _, order := range lostOrders {
+			select {
+			case c.hallBtnRx <- order:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// The lifecycle of the hall orders. We take over the ones that were assigned by the previous master
+	// and the ones that it did not confirm yet
+	// They are keyed by their button: a second press of the same button is the same order, and keeps the first id
+	hallOrders := make(map[orderKey]hallOrderRecord)
+	for id, state := range allStates {
+		if state.Behavior != "Uninitialized" {
+			for _, order := range extractHallOrders(state.LocalRequests) {
+				hallOrders[order.key()] = newAssignment(allStates, id, order)
+			}
+		}
+	}
+	c.mutex_backup.Lock()
+	for _, order := range c.pendingHallOrders {
+		if _, exists := hallOrders[order.key()]; !exists {
+			hallOrders[order.key()] = hallOrderRecord{Order: order, Status: unconfirmed}
+		}
+	}
+	c.mutex_backup.Unlock()
+
+	defer c.setHallOrderMetrics(nil) // We do not track them anymore
+
+	hallLightsTicker := time.NewTicker(hallLightsRate)
+	defer hallLightsTicker.Stop()
+	watchdogTicker := time.NewTicker(watchdogRate)
+	defer watchdogTicker.Stop()
+	trafficTicker := time.NewTicker(trafficRate)
+	defer trafficTicker.Stop()
+
+	// The traffic detection starts over with each master (see traffic.go)
+	trafficCalls := []trafficCall{} // The new hall calls of the window
+	detectedTraffic := trafficNormal
+	var lastLobbyCalls [numElev]time.Time // When each car was given a call going up from the lobby
+	var idleSince [numElev]time.Time      // When each car became idle, for the parking (see parking.go)
+
+	// Broadcasts the confirmed hall orders (whose lights must be on) and the unconfirmed ones
+	broadcastHallLights := func() bool {
+		if c.isIsolated() { // Nobody can confirm them, our lights are handled locally
+			return true
+		}
+		msg := HallLightsMsg{From: c.id, Orders: []Order{}, Pending: []Order{}, OutOfService: c.outOfServiceList()}
+		msg.FireRecall, msg.RecallFloor = c.fireRecallState()
+		msg.TrafficMode, msg.TrafficSource, msg.TrafficForced = c.trafficState()
+		for _, record := range hallOrders {
+			if record.Status == unconfirmed {
+				msg.Pending = append(msg.Pending, record.Order)
+			} else {
+				msg.Orders = append(msg.Orders, record.Order)
+			}
+		}
+		select {
+		case c.hallLightsTx <- msg:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	// Assigns a hall order to one of the candidates and records the assignment. Returns false if ctx was cancelled
+	assign := func(order Order, candidates []int) bool {
+		candidates = servingElevators(allStates, candidates, order.Floor) // See zoning.go
+		candidates = withoutPickupAt(hallOrders, candidates, order)       // See destinationDispatch.go
+		fromLobby := order.Floor == c.lobbyFloor && order.Direction == up
+		if fromLobby && c.currentTrafficMode() == trafficUpPeak {
+			candidates = lobbyCandidates(allStates, candidates, c.lobbyFloor, lastLobbyCalls)
+		}
+		if record := hallOrders[order.key()]; len(candidates) == 0 && record.Status == assigned {
+			// Nobody else stops at its floor, its elevator keeps it (like the watchdog does)
+			record.AssignedAt = time.Now()
+			hallOrders[order.key()] = record
+			return true
+		} else if len(candidates) == 0 {
+			c.logEvent(logMaster, "Hall order %s dropped, no elevator serves floor %d", order.Name(), order.Floor)
+			delete(hallOrders, order.key())
+			return broadcastHallLights() // Its light goes off
+		}
+		order, ok := c.assignHallOrder(ctx, allStates, order, candidates)
+		if ok {
+			elevator := order.History[len(order.History)-1]
+			if fromLobby {
+				lastLobbyCalls[elevator] = time.Now()
+			}
+			hallOrders[order.key()] = newAssignment(allStates, elevator, order)
+			c.logger(logMaster).Debugf("Hall order %s (%s) assigned to elevator %d", order.Name(), order.Id, elevator)
+			if len(order.Destinations) > 0 { // Its passengers are told which car to take (again if it is re-assigned)
+				ok = c.announceDestinationGroup(ctx, order.Id, elevator, order)
+			}
+		}
+		return ok
+	}
+
+	// Gives a confirmed destination call (see destinationDispatch.go) to a group. Returns false if ctx was cancelled
+	dispatchDestination := func(order Order) bool {
+		destination := order.Destinations[0]
+		candidates := servingElevators(allStates, c.candidateElevators(allStates, -1), destination)
+		if record, ok := joinDestinationGroup(hallOrders, candidates, order, destination); ok {
+			// The passenger joins a group going to nearby floors
+			delete(hallOrders, order.key())
+			record.Order.Destinations = append(append([]int{}, record.Order.Destinations...), destination)
+			hallOrders[record.Order.key()] = record
+			return c.regroupHallOrder(ctx, record.Elevator, record.Order) && c.announceDestinationGroup(ctx, order.Id, record.Elevator, record.Order)
+		}
+
+		// Else in a new group, for the car that takes it at the lowest cost
+		trafficCalls = append(trafficCalls, trafficCall{At: time.Now(), Floor: order.Floor, Direction: order.Direction})
+		candidates = withoutPickupAt(hallOrders, servingElevators(allStates, candidates, order.Floor), order)
+		if best := cheapestForDestination(allStates, candidates, order, destination); best != -1 {
+			return assign(order, []int{best})
+		}
+		c.logEvent(logMaster, "Destination call %s (floor %d to %d) dropped, no elevator can take it", order.Id, order.Floor, destination)
+		delete(hallOrders, order.key())
+		return c.refuseDestinationCall(ctx, order.Id, order)
+	}
+
+	// Updates the traffic mode and sends the idle cars to the floors where they wait: the ones of the traffic mode
+	// during a peak, else the ones of the parking policy once they have been idle for a while
+	updateTraffic := func() bool {
+		var rates [][2]float64
+		trafficCalls, rates = trafficRates(trafficCalls, time.Now())
+		detectedTraffic = detectTrafficMode(trafficCalls, c.lobbyFloor, detectedTraffic)
+		mode := c.updateTrafficMode(detectedTraffic, rates)
+
+		idle := c.idleCars(allStates)
+		floors := parkingFloors(allStates, idle, mode, c.lobbyFloor)
+		if ready := idleFor(idle, &idleSince, c.parkingDelay); mode == trafficNormal {
+			floors = c.policyParkingFloors(allStates, idle, ready, rates)
+		}
+		for id, floor := range floors {
+			c.logger(logMaster).Debugf("Parking elevator %d at floor %d", id, floor)
+			select {
+			case c.parkTx <- ParkMsg{Id: id, Floor: floor}:
+			case <-ctx.Done():
+				return false
+			}
+		}
+		return true
+	}
+
+	go c.spamSlaves(ctx)           // Send the state of the elevators to the slaves periodically
+	go c.receiveSpamFromSlave(ctx) // Receive the state of the elevators from the slaves periodically
+
+	for {
+		select {
+		case order := <-c.hallBtnRx:
+			record, exists := hallOrders[order.key()]
+			switch {
+			case !validHallOrder(order): // e.g. from another version of the client
+				c.logger(logMaster).Warnf("Hall order %s ignored, there is no such button", order.Id)
+			case c.isFireRecall(): // The hall buttons are ignored until the recall is reset
+				c.logger(logMaster).Debugf("Hall order %s ignored during the fire recall", order.Name())
+			case !exists:
+				// A new hall order must be known by the PrimaryBackup before it is lit and assigned
+				hallOrders[order.key()] = hallOrderRecord{Order: order, Status: unconfirmed}
+				trafficCalls = append(trafficCalls, trafficCall{At: time.Now(), Floor: order.Floor, Direction: order.Direction})
+				if !broadcastHallLights() {
+					return
+				}
+			case record.Status == unconfirmed: // Still waiting for the confirmation
+			default:
+				// The order is re-assigned (e.g. its elevator stopped or left), it stays lit and keeps its id
+				if !assign(record.Order, c.candidateElevators(allStates, -1)) {
+					return
+				}
+			}
+
+		case call := <-c.destinationCallRx: // A destination entered at a hall (see destinationDispatch.go)
+			order := destinationOrder(call)
+			if call.Destination < 0 || call.Destination >= numFloors || call.Destination == call.Floor || !validHallOrder(order) {
+				c.logger(logMaster).Warnf("Destination call %s ignored, there is no such floor", call.Id)
+				continue
+			}
+			if _, exists := hallOrders[order.key()]; exists {
+				continue
+			}
+			if c.isFireRecall() {
+				c.logger(logMaster).Debugf("Destination call %s ignored during the fire recall", call.Id)
+				if !c.refuseDestinationCall(ctx, call.Id, order) {
+					return
+				}
+				continue
+			}
+			// Like the other hall orders, it must be known by the PrimaryBackup before it is given to a group
+			hallOrders[order.key()] = hallOrderRecord{Order: order, Status: unconfirmed}
+			if !broadcastHallLights() {
+				return
+			}
+
+		case a := <-c.singleStateRx: // A state update on singleStateRx
+
+			// Send the state update for detecting motor stop
+			select {
+			case newElevatorActivity <- elevatorActivity{
+				id:            a.Id,
+				timestamp:     time.Now(),
+				localRequests: a.State.LocalRequests,
+			}:
+			case <-ctx.Done():
+				return
+			}
+
+			// Compare the old and new state and send a message on orderCompleted so that the order lights get taken care of
+			// Assume that we dont delete and add hallOrders at the same time
+			oldStateOrders := allStates[a.Id].LocalRequests
+			newStateOrders := a.State.LocalRequests
+
+			oldHallOrders := extractHallOrders(oldStateOrders)
+			newHallOrders := extractHallOrders(newStateOrders)
+			length_old := len(oldHallOrders)
+			length_new := len(newHallOrders)
+
+			// A car that leaves the group dispatch (e.g. independent service) hands its hall orders back: they are
+			// not served, they go to the other elevators (below, once it is not active anymore)
+			leavesDispatch := dispatchable(allStates[a.Id].Mode) && !dispatchable(a.State.Mode)
+			handedBack := []Order{}
+
+			if length_new < length_old && leavesDispatch {
+				handedBack = findUniqueOrders(oldHallOrders, newHallOrders)
+			} else if length_new < length_old {
+				removed_hallOrders := []Order{}
+				for _, order := range findUniqueOrders(oldHallOrders, newHallOrders) {
+					if record, exists := hallOrders[order.key()]; exists && record.Status == assigned && record.Elevator != a.Id {
+						continue // Re-assigned by the watchdog, the elevator dropped it when it was cancelled
+					}
+					delete(hallOrders, order.key()) // The order is served
+					removed_hallOrders = append(removed_hallOrders, order)
+				}
+				if len(removed_hallOrders) > 0 {
+					c.countCompletedHallOrders(len(removed_hallOrders))
+					select {
+					case c.hallOrderCompletedTx <- removed_hallOrders:
+					case <-ctx.Done():
+						return
+					}
+					select { // Send the id of the elevator that completed the hall order
+					case idCompletedHallOrderForTimer <- a.Id:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+
+			// Update our list of allStates with the new state and send new states list to the primary backup
+			previousMode := allStates[a.Id].Mode
+			allStates[a.Id] = a.State
+
+			// A car that leaves the normal mode (e.g. firefighter operation) is not given hall orders anymore
+			if dispatchable(previousMode) != dispatchable(a.State.Mode) && !c.setDispatchable(ctx, a.Id, dispatchable(a.State.Mode)) {
+				return
+			}
+			for _, order := range handedBack {
+				record, exists := hallOrders[order.key()]
+				if !exists { // e.g. cancelled by the fire recall
+					continue
+				}
+				if candidates := c.candidateElevators(allStates, a.Id); len(candidates) > 0 && !assign(record.Order, candidates) {
+					return
+				}
+			}
+
+			c.mutex_backup.Lock()
+			c.backupStates = allStates
+			c.mutex_backup.Unlock()
+
+			select {
+			case c.backupStatesTx <- allStates:
+			case <-ctx.Done():
+				return
+			}
+
+		case id := <-c.askForCabOrdersRx:
+
+			// Master sends cab orders to the new elevator
+			lostCabOrders := []Order{}
+			for _, order := range c.backupStates[id].LocalRequests {
+				if order.OrderType == cab {
+					lostCabOrders = append(lostCabOrders, order)
+				}
+			}
+
+			// Send the cab orders to the new elevator
+			select {
+			case c.retrieveCabOrdersTx <- CabOrderMsg{id, lostCabOrders}:
+			case <-ctx.Done():
+				return
+			}
+
+		case a := <-c.hallOrdersAckRx:
+			if !validElevatorId(a.Id) {
+				c.logger(logMaster).Warnf("Acknowledgement of elevator %d ignored, there is no such elevator", a.Id)
+				continue
+			}
+
+			// The orders known by the master and the PrimaryBackup are confirmed, and can be assigned
+			newlyConfirmed := confirmHallOrders(hallOrders, a, c.id)
+			for _, order := range newlyConfirmed {
+				if len(order.Destinations) > 0 { // A destination call
+					if !dispatchDestination(order) {
+						return
+					}
+				} else if !assign(order, c.candidateElevators(allStates, -1)) {
+					return
+				}
+			}
+			if len(newlyConfirmed) > 0 && !broadcastHallLights() { // Turn on their lights right away
+				return
+			}
+
+		case cmd := <-c.masterCommands: // A command of the API
+			var err error
+			switch cmd.Kind {
+			case "cancel":
+				var cancel *CancelOrderMsg
+				cancel, err = cancelOrder(hallOrders, allStates, cmd.OrderId)
+				if cancel != nil { // Tell the elevators that hold it
+					select {
+					case c.cancelOrderTx <- *cancel:
+					case <-ctx.Done():
+						return
+					}
+				}
+
+			case "service":
+				outOfService := c.outOfServiceList()
+				if !cmd.InService {
+					outOfService = append(outOfService, cmd.Elevator)
+				} else {
+					for i, id := range outOfService {
+						if id == cmd.Elevator {
+							outOfService = append(outOfService[:i], outOfService[i+1:]...)
+							break
+						}
+					}
+				}
+				c.setOutOfService(outOfService)
+				if cmd.InService {
+					c.logEvent(logMaster, "Elevator %d is back in service", cmd.Elevator)
+				} else {
+					c.logEvent(logMaster, "Elevator %d is out of service", cmd.Elevator)
+				}
+
+				// Its hall orders are given to the other elevators, like the watchdog does
+				for _, record := range hallOrders {
+					if cmd.InService || record.Status != assigned || record.Elevator != cmd.Elevator {
+						continue
+					}
+					if candidates := c.candidateElevators(allStates, cmd.Elevator); len(candidates) > 0 {
+						if !assign(record.Order, candidates) {
+							return
+						}
+					}
+				}
+
+			case "traffic":
+				c.forceTrafficMode(cmd.Mode)
+				if !updateTraffic() {
+					return
+				}
+			}
+			cmd.reply <- err
+			if !broadcastHallLights() {
+				return
+			}
+
+		case r := <-c.fireRecallRx: // The fire recall switch of an elevator (or the API)
+			c.switchFireRecall(hallOrders, r.Active)
+			if !broadcastHallLights() {
+				return
+			}
+
+		case a := <-c.orderServedRx: // Served orders, for the wait and journey time statistics
+			if !validElevatorId(a.Id) {
+				c.logger(logMaster).Warnf("Served orders of elevator %d ignored, there is no such elevator", a.Id)
+				continue
+			}
+			c.orderStats.record(a)
+
+		case <-hallLightsTicker.C: // Broadcast the hall orders whose lights must be on
+			c.setHallOrderMetrics(hallOrders)
+			if !broadcastHallLights() {
+				return
+			}
+
+		case <-trafficTicker.C: // Follow the traffic of the building
+			if !updateTraffic() {
+				return
+			}
+
+		case <-watchdogTicker.C: // Re-assign the hall orders that are not served in time
+			others := func(excluded int) []int { return c.candidateElevators(allStates, excluded) }
+			for key, candidates := range overdueHallOrders(hallOrders, c.hallOrderTimeoutFactor, time.Now(), others) {
+				record := hallOrders[key]
+				c.logEvent(logMaster, "Hall order %s (floor %d) was not served by elevator %d in %v, re-assigning it",
+					record.Order.Id, record.Order.Floor, record.Elevator, time.Duration(c.hallOrderTimeoutFactor*float64(record.Estimate)))
+				select { // Its elevator drops it, so that it is not served (and completed) twice
+				case c.cancelOrderTx <- CancelOrderMsg{Id: record.Elevator, Order: record.Order}:
+				case <-ctx.Done():
+					return
+				}
+				if !assign(record.Order, candidates) {
+					return
+				}
+				c.mutex_reassignments.Lock()
+				c.hallOrderReassignments++
+				c.mutex_reassignments.Unlock()
+			}
+
+		case r := <-c.rejoinRx: // An elevator comes back after a network partition
+			if r.Id == c.id {
+				continue
+			}
+			if !validElevatorId(r.Id) {
+				c.logger(logMaster).Warnf("Rejoin of elevator %d ignored, there is no such elevator", r.Id)
+				continue
+			}
+			if c.isRejoining() && r.Id < c.id {
+				// We were both isolated masters, the one with the lowest id keeps the role
+				continue
+			}
+			c.stopRejoin() // The other elevator joins us
+			c.setIsolated(false)
+
+			missingCabOrders := mergeRejoin(&allStates, r)
+			for _, order := range r.Served {
+				delete(hallOrders, order.key())
+			}
+			c.countCompletedHallOrders(len(r.Served))
+			for _, order := range extractHallOrders(allStates[r.Id].LocalRequests) {
+				hallOrders[order.key()] = newAssignment(allStates, r.Id, order) // Known by the master and the elevator that rejoins
+			}
+
+			c.mutex_backup.Lock()
+			c.backupStates = allStates
+			c.mutex_backup.Unlock()
+
+			// Give it its role: it does not keep the master role it may have taken while isolated
+			c.mutex_peers.Lock()
+			cluster := []peers.ElevIdentity{}
+			for _, peer := range c.peers {
+				if peer.Id == r.Id {
+					peer.Role = "Regular"
+				}
+				cluster = append(cluster, peer)
+			}
+			c.mutex_peers.Unlock()
+			role, ok := assignRoles(cluster, c.id, "Master")[r.Id]
+			if !ok {
+				role = "Regular"
+			}
+
+			c.logEvent(logMaster, "Elevator %d rejoins as %s (%d hall orders served offline)", r.Id, role, len(r.Served))
+
+			select {
+			case c.rejoinAckTx <- RejoinAckMsg{Id: r.Id, From: c.id, Role: role}:
+			case <-ctx.Done():
+				return
+			}
+
+			if len(r.Served) > 0 { // Turn off the lights of the hall orders it served
+				select {
+				case c.hallOrderCompletedTx <- r.Served:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			if len(missingCabOrders) > 0 { // Give it back the cab orders it does not know about
+				select {
+				case c.retrieveCabOrdersTx <- CabOrderMsg{r.Id, missingCabOrders}:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+		case <-ctx.Done():
+			return
+		}
+
+	}
+}
+
+func (c *Client) primaryBackupRoutine(ctx context.Context) {
+	c.logger(logBackup).Infof("PrimaryBackup routine started")
+
+	// To-Do: update the global backupStates
+	go c.transport.Receiver(ctx, AllStates_PORT, c.backupStatesRx) // Used to receive the states from the master
+
+	for {
+		select {
+		case a := <-c.backupStatesRx:
+			// Update the global backupStates
+			c.mutex_backup.Lock()
+			c.backupStates = a
+			c.mutex_backup.Unlock()
+		case <-ctx.Done():
+			return
+		}
+	}
+
+}
+
+// Adds an elevator to the active ones, or removes it, and sends the list to the other elevators.
+// Returns false if ctx was cancelled
+func (c *Client) setDispatchable(ctx context.Context, id int, active bool) bool {
+	c.mutex_activeElevators.Lock()
+	if active && !c.isElevatorActive(id) {
+		c.activeElevators = append(c.activeElevators, id)
+	} else if !active {
+		c.removeElevator(id)
+	}
+	c.activeElevators = sortElevators(c.activeElevators)
+	activeElevators := append([]int{}, c.activeElevators...)
+	c.mutex_activeElevators.Unlock()
+
+	if active {
+		c.logEvent(logMaster, "Elevator %d takes hall orders again", id)
+	} else {
+		c.logEvent(logMaster, "Elevator %d does not take hall orders in its mode", id)
+	}
+	select {
+	case c.activeElevatorsChannelTx <- activeElevators:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Returns the elevators a hall order can be assigned to: the active ones, except excluded (-1 for none) and the
+// ones that did not send their state yet (they would cost nothing and win every order)
+func (c *Client) candidateElevators(allStates [numElev]ElevState, excluded int) []int {
+	c.mutex_activeElevators.Lock()
+	defer c.mutex_activeElevators.Unlock()
+
+	candidates := []int{}
+	inService := []int{}
+	for _, id := range c.activeElevators {
+		if id != excluded && allStates[id].Behavior != "Uninitialized" {
+			candidates = append(candidates, id)
+			if !c.isOutOfService(id) {
+				inService = append(inService, id)
+			}
+		}
+	}
+	if len(inService) > 0 { // The elevators out of service only take hall orders when nobody else can
+		return inService
+	}
+	if len(candidates) == 0 && excluded != c.id {
+		// No working elevator known (e.g. we just took over and never received the list), keep the order ourselves
+		return []int{c.id}
+	}
+	return candidates
+}
+
+// Assigns a hall order to the candidate with the lowest cost, and sends it to this elevator.
+// Returns the order with this elevator at the end of its History, and false if ctx was cancelled
+func (c *Client) assignHallOrder(ctx context.Context, allStates [numElev]ElevState, order Order, activeElevators []int) (Order, bool) {
+	var workingElevNb = len(activeElevators)
+	workingElevs := make([]ElevState, workingElevNb)
+	// Remember which index coresponds to which elevator id
+	// This is important for sending the hall order to the correct elevator
+	indexMapping := []int{} // Contains the id of the working elevators in the order they are in workingElevs
+	for i, id := range activeElevators {
+		workingElevs[i] = allStates[id]
+		indexMapping = append(indexMapping, id)
+	}
+
+	// Calculate the cost of assigning the order to each elevator
+	orderCosts := make([]float64, workingElevNb)
+
+	for i, state := range workingElevs {
+		if state.Behavior != "Uninitialized" {
+			cost := calculateCost(state, order)
+			orderCosts[i] = cost
+		}
+	}
+
+	// Find the elevator with the lowest cost
+	bestElevator := 0 // Id of the best elevator (relative to workingElevs)
+	for i, cost := range orderCosts {
+		if cost < orderCosts[bestElevator] {
+			bestElevator = i
+		}
+	}
+
+	// Retrieve the id of the best elevator (relative to allStates)
+	bestElevator = indexMapping[bestElevator]
+
+	// Update backupStates with the new order
+	order = order.assignedTo(bestElevator)
+	c.mutex_backup.Lock()
+	c.backupStates[bestElevator].LocalRequests = append(c.backupStates[bestElevator].LocalRequests, order)
+	c.mutex_backup.Unlock()
+
+	HallOrderMessage := HallOrderMsg{bestElevator, order}
+
+	// Send the order to a slave
+	select {
+	case c.hallOrderTx <- HallOrderMessage:
+		return order, true
+	case <-ctx.Done():
+		return order, false
+	}
+}
+
+// Confirms the unconfirmed hall orders of an acknowledgement of the PrimaryBackup (the master and it know of them)
+// and returns them, to be assigned. Only the PrimaryBackup confirms orders, the other acknowledgements (and ours)
+// are ignored
+func confirmHallOrders(hallOrders map[orderKey]hallOrderRecord, ack HallOrdersAckMsg, self int) []Order {
+	confirmedOrders := []Order{}
+	if ack.Id == self || ack.Role != "PrimaryBackup" {
+		return confirmedOrders
+	}
+	for _, order := range ack.Orders {
+		if record, exists := hallOrders[order.key()]; exists && record.Status == unconfirmed {
+			record.Status = confirmed
+			hallOrders[order.key()] = record
+			confirmedOrders = append(confirmedOrders, record.Order)
+		}
+	}
+	return confirmedOrders
+}
+
+// Returns the assigned hall orders that were not served within factor times their estimated service time, with the
+// elevators that can take them instead (see candidates). The ones nobody else can take are given more time: their
+// elevator keeps them, as if they had just been assigned
+func overdueHallOrders(hallOrders map[orderKey]hallOrderRecord, factor float64, now time.Time, candidates func(excluded int) []int) map[orderKey][]int {
+	overdue := make(map[orderKey][]int)
+	for key, record := range hallOrders {
+		timeout := time.Duration(factor * float64(record.Estimate))
+		if record.Status != assigned || now.Sub(record.AssignedAt) < timeout {
+			continue
+		}
+		others := candidates(record.Elevator)
+		if len(others) == 0 {
+			record.AssignedAt = now
+			hallOrders[key] = record
+			continue
+		}
+		overdue[key] = others
+	}
+	return overdue
+}
+
+// Records the assignment of a hall order to an elevator, with the time it should take to serve it
+func newAssignment(allStates [numElev]ElevState, id int, order Order) hallOrderRecord {
+	return hallOrderRecord{Order: order, Status: assigned, Elevator: id, AssignedAt: time.Now(), Estimate: estimateServiceTime(allStates[id], order)}
+}
+
+// Estimates the time an elevator needs to serve an order: it goes through its queue (in order) until it reaches
+// the order, or then goes to the order if it is not in its queue yet. Each stop keeps the door open
+func estimateServiceTime(elevator ElevState, order Order) time.Duration {
+	floor := elevator.Floor
+	if floor < 0 || floor >= numFloors { // Unknown position
+		floor = 0
+	}
+
+	distance := 0
+	stops := 0
+	found := false
+	for _, request := range elevator.LocalRequests {
+		if request.Floor != floor {
+			distance += int(math.Abs(float64(request.Floor - floor)))
+			floor = request.Floor
+			stops++
+		}
+		if request.SameButton(order) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		distance += int(math.Abs(float64(order.Floor - floor)))
+		stops++
+	}
+	if stops == 0 { // The order is at our floor
+		stops = 1
+	}
+
+	return time.Duration(distance)*travelTimeBetweenFloors + time.Duration(stops)*doorOpenTime
+}
+
+// Merges the orders of an elevator that rejoins the cluster into allStates:
+// the hall orders it served while isolated are removed from the other elevators, and it keeps its own orders,
+// except the hall orders that were re-assigned to another elevator when it was lost.
+// Returns the cab orders that we kept for it and that it does not know about
+func mergeRejoin(allStates *[numElev]ElevState, r RejoinMsg) []Order {
+	served := make(map[orderKey]bool)
+	for _, order := range r.Served {
+		served[order.key()] = true
+	}
+
+	takenByOthers := make(map[orderKey]bool)
+	for id := range allStates {
+		if id == r.Id {
+			continue
+		}
+		remaining := []Order{}
+		for _, order := range allStates[id].LocalRequests {
+			if served[order.key()] {
+				continue
+			}
+			if order.OrderType == hall {
+				takenByOthers[order.key()] = true
+			}
+			remaining = append(remaining, order)
+		}
+		allStates[id].LocalRequests = remaining
+	}
+
+	ownOrders := make(map[orderKey]bool)
+	state := r.States[r.Id]
+	state.LocalRequests = []Order{}
+	for _, order := range r.Orders {
+		ownOrders[order.key()] = true
+		if order.OrderType == hall && takenByOthers[order.key()] {
+			continue
+		}
+		state.LocalRequests = append(state.LocalRequests, order)
+	}
+
+	missingCabOrders := []Order{}
+	for _, order := range allStates[r.Id].LocalRequests {
+		if order.OrderType == cab && !ownOrders[order.key()] {
+			missingCabOrders = append(missingCabOrders, order)
+		}
+	}
+
+	allStates[r.Id] = state
+	return missingCabOrders
+}
+
+// Cost function for assigning an order to an elevator.
+func calculateCost(elevator ElevState, order Order) float64 {
+	// Base cost is the absolute distance from the elevator to the order
+	cost := 1 + math.Abs(float64(order.Floor-elevator.Floor))
+
+	// If elevator is idle, prioritize it
+	if elevator.Behavior == "idle" {
+		cost *= 0.5 // Strongly favor idle elevators
+	}
+
+	if elevator.Behavior == "moving" && order.Floor == elevator.Floor {
+		cost *= 2
+	}
+
+	// If moving in the same direction and order is on the way, lower cost
+	if (elevator.Direction == "up" && order.Direction == 1 && order.Floor >= elevator.Floor) ||
+		(elevator.Direction == "down" && order.Direction == -1 && order.Floor <= elevator.Floor) {
+		cost *= 0.5 // Favor elevators already moving toward the order
+	}
+
+	// If moving in the opposite direction, penalize cost
+	if (elevator.Direction == "up" && order.Direction == -1) ||
+		(elevator.Direction == "down" && order.Direction == 1) {
+		cost *= 1.5 // Penalize opposite direction
+	}
+
+	return cost
+}
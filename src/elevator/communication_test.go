@@ -0,0 +1,211 @@
+package elevator
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func hallOrderAt(floor int, direction OrderDirection) Order {
+	return Order{Floor: floor, Direction: direction, OrderType: hall}
+}
+
+func cabOrderAt(floor int) Order {
+	return Order{Floor: floor, OrderType: cab}
+}
+
+func TestMergeRejoin(t *testing.T) {
+	tests := []struct {
+		name        string
+		states      [numElev][]Order // The LocalRequests known by the master
+		rejoin      RejoinMsg        // Elevator 2 rejoins
+		wantStates  [numElev][]Order
+		wantMissing []Order
+	}{
+		{
+			name:       "nothing happened while isolated",
+			states:     [numElev][]Order{{}, {}, {cabOrderAt(1)}},
+			rejoin:     RejoinMsg{Id: 2, Orders: []Order{cabOrderAt(1)}},
+			wantStates: [numElev][]Order{{}, {}, {cabOrderAt(1)}},
+		},
+		{
+			name:       "the hall orders served offline are removed from the others",
+			states:     [numElev][]Order{{hallOrderAt(3, down), cabOrderAt(0)}, {hallOrderAt(1, up)}, {}},
+			rejoin:     RejoinMsg{Id: 2, Served: []Order{hallOrderAt(3, down), hallOrderAt(1, up)}},
+			wantStates: [numElev][]Order{{cabOrderAt(0)}, {}, {}},
+		},
+		{
+			name:       "a hall order re-assigned while it was lost stays with the other elevator",
+			states:     [numElev][]Order{{hallOrderAt(2, up)}, {}, {hallOrderAt(2, up)}},
+			rejoin:     RejoinMsg{Id: 2, Orders: []Order{hallOrderAt(2, up), hallOrderAt(1, down)}},
+			wantStates: [numElev][]Order{{hallOrderAt(2, up)}, {}, {hallOrderAt(1, down)}},
+		},
+		{
+			name:        "the cab orders it does not know about are sent back to it",
+			states:      [numElev][]Order{{}, {}, {cabOrderAt(3), cabOrderAt(0)}},
+			rejoin:      RejoinMsg{Id: 2, Orders: []Order{cabOrderAt(0)}},
+			wantStates:  [numElev][]Order{{}, {}, {cabOrderAt(0)}},
+			wantMissing: []Order{cabOrderAt(3)},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var allStates [numElev]ElevState
+			for id, requests := range test.states {
+				allStates[id].LocalRequests = requests
+			}
+			missing := mergeRejoin(&allStates, test.rejoin)
+
+			for id, want := range test.wantStates {
+				if got := allStates[id].LocalRequests; len(got) != len(want) || (len(want) > 0 && !reflect.DeepEqual(got, want)) {
+					t.Errorf("elevator %d: LocalRequests = %v, want %v", id, got, want)
+				}
+			}
+			if len(missing) != len(test.wantMissing) || (len(missing) > 0 && !reflect.DeepEqual(missing, test.wantMissing)) {
+				t.Errorf("missing cab orders = %v, want %v", missing, test.wantMissing)
+			}
+		})
+	}
+}
+
+func TestConfirmHallOrders(t *testing.T) {
+	const master = 0
+	tests := []struct {
+		name       string
+		statuses   map[orderKey]HallOrderStatus // The hall orders known by the master
+		ack        HallOrdersAckMsg
+		want       []Order
+		wantStatus map[orderKey]HallOrderStatus
+	}{
+		{
+			name:       "the PrimaryBackup confirms the orders it knows of",
+			statuses:   map[orderKey]HallOrderStatus{hallOrderAt(1, up).key(): unconfirmed, hallOrderAt(2, down).key(): unconfirmed},
+			ack:        HallOrdersAckMsg{Id: 1, Role: "PrimaryBackup", Orders: []Order{hallOrderAt(1, up)}},
+			want:       []Order{hallOrderAt(1, up)},
+			wantStatus: map[orderKey]HallOrderStatus{hallOrderAt(1, up).key(): confirmed, hallOrderAt(2, down).key(): unconfirmed},
+		},
+		{
+			name:       "a Regular confirms nothing",
+			statuses:   map[orderKey]HallOrderStatus{hallOrderAt(1, up).key(): unconfirmed},
+			ack:        HallOrdersAckMsg{Id: 2, Role: "Regular", Orders: []Order{hallOrderAt(1, up)}},
+			want:       []Order{},
+			wantStatus: map[orderKey]HallOrderStatus{hallOrderAt(1, up).key(): unconfirmed},
+		},
+		{
+			name:       "our own acknowledgement confirms nothing",
+			statuses:   map[orderKey]HallOrderStatus{hallOrderAt(1, up).key(): unconfirmed},
+			ack:        HallOrdersAckMsg{Id: master, Role: "PrimaryBackup", Orders: []Order{hallOrderAt(1, up)}},
+			want:       []Order{},
+			wantStatus: map[orderKey]HallOrderStatus{hallOrderAt(1, up).key(): unconfirmed},
+		},
+		{
+			name:       "the orders already confirmed or assigned are not assigned again",
+			statuses:   map[orderKey]HallOrderStatus{hallOrderAt(1, up).key(): confirmed, hallOrderAt(2, down).key(): assigned},
+			ack:        HallOrdersAckMsg{Id: 1, Role: "PrimaryBackup", Orders: []Order{hallOrderAt(1, up), hallOrderAt(2, down)}},
+			want:       []Order{},
+			wantStatus: map[orderKey]HallOrderStatus{hallOrderAt(1, up).key(): confirmed, hallOrderAt(2, down).key(): assigned},
+		},
+		{
+			name:       "the orders the master does not know of are ignored",
+			statuses:   map[orderKey]HallOrderStatus{},
+			ack:        HallOrdersAckMsg{Id: 1, Role: "PrimaryBackup", Orders: []Order{hallOrderAt(3, down)}},
+			want:       []Order{},
+			wantStatus: map[orderKey]HallOrderStatus{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hallOrders := make(map[orderKey]hallOrderRecord)
+			for key, status := range test.statuses {
+				hallOrders[key] = hallOrderRecord{Order: hallOrderAt(key.Floor, key.Direction), Status: status}
+			}
+			if got := confirmHallOrders(hallOrders, test.ack, master); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("confirmHallOrders() = %v, want %v", got, test.want)
+			}
+			if len(hallOrders) != len(test.wantStatus) {
+				t.Errorf("the master knows of %d hall orders, want %d", len(hallOrders), len(test.wantStatus))
+			}
+			for key, want := range test.wantStatus {
+				if got := hallOrders[key].Status; got != want {
+					t.Errorf("status of %+v = %v, want %v", key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestOverdueHallOrders(t *testing.T) {
+	const factor = 3
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local)
+	tests := []struct {
+		name           string
+		status         HallOrderStatus
+		age            time.Duration // Since it was assigned, to elevator 0 with an estimate of 10 s
+		available      []int         // The elevators that can take hall orders
+		wantCandidates []int         // Nil if it is not re-assigned
+		wantExtended   bool
+	}{
+		{"served in time", assigned, 29 * time.Second, []int{0, 1, 2}, nil, false},
+		{"not served in time", assigned, 30 * time.Second, []int{0, 1, 2}, []int{1, 2}, false},
+		{"not assigned yet", confirmed, time.Minute, []int{0, 1, 2}, nil, false},
+		{"not confirmed yet", unconfirmed, time.Minute, []int{0, 1, 2}, nil, false},
+		{"nobody else can take it", assigned, time.Minute, []int{0}, nil, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			order := hallOrderAt(1, up)
+			hallOrders := map[orderKey]hallOrderRecord{
+				order.key(): {Order: order, Status: test.status, Elevator: 0, AssignedAt: now.Add(-test.age), Estimate: 10 * time.Second},
+			}
+			candidates := func(excluded int) []int {
+				others := []int{}
+				for _, id := range test.available {
+					if id != excluded {
+						others = append(others, id)
+					}
+				}
+				return others
+			}
+
+			overdue := overdueHallOrders(hallOrders, factor, now, candidates)
+			if got, ok := overdue[order.key()]; ok != (test.wantCandidates != nil) || !reflect.DeepEqual(got, test.wantCandidates) {
+				t.Errorf("overdueHallOrders() = %v, want candidates %v", overdue, test.wantCandidates)
+			}
+			if extended := hallOrders[order.key()].AssignedAt.Equal(now); extended != test.wantExtended {
+				t.Errorf("AssignedAt extended = %v, want %v", extended, test.wantExtended)
+			}
+		})
+	}
+}
+
+func TestEstimateServiceTime(t *testing.T) {
+	tests := []struct {
+		name  string
+		state ElevState
+		order Order
+		want  time.Duration
+	}{
+		{"at our floor", ElevState{Floor: 2}, hallOrderAt(2, up), doorOpenTime},
+		{"two floors away", ElevState{Floor: 0}, hallOrderAt(2, down), 2*travelTimeBetweenFloors + doorOpenTime},
+		{
+			"behind another stop of the queue",
+			ElevState{Floor: 0, LocalRequests: []Order{cabOrderAt(3), hallOrderAt(1, down)}},
+			hallOrderAt(1, down),
+			5*travelTimeBetweenFloors + 2*doorOpenTime,
+		},
+		{
+			"not in the queue yet, after it",
+			ElevState{Floor: 0, LocalRequests: []Order{cabOrderAt(2)}},
+			hallOrderAt(1, up),
+			3*travelTimeBetweenFloors + 2*doorOpenTime,
+		},
+		{"unknown position, from the ground floor", ElevState{Floor: -1}, hallOrderAt(1, up), travelTimeBetweenFloors + doorOpenTime},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := estimateServiceTime(test.state, test.order); got != test.want {
+				t.Errorf("estimateServiceTime() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+// This file contains the optional live dashboard of the client (see Config.DashboardAddr).
+// It is read-only: every elevator shows the view of the cluster it got from the master
+package elevator
+
+import (
+	"Network-go/network/peers"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+//go:embed dashboard.html
+var dashboardPage []byte
+
+type dashboardEvent struct {
+	Seq  int
+	Time time.Time
+	Text string
+}
+
+type dashboardCar struct {
+	Id            int
+	Active        bool // Able to take new orders
+	InService     bool // Not put out of service through the API
+	Behavior      string
+	Mode          string // normal, fireRecall, firefighter, independent or inspection
+	Floor         int
+	Direction     string
+	DoorOpen      bool
+	LocalRequests []Order
+	ServedFloors  []int // Empty for all of them
+}
+
+type dashboardHallCall struct {
+	Floor     int
+	Direction string // up or down
+	Status    string // unconfirmed, confirmed or assigned
+	Elevator  int    // The elevator it is assigned to, -1 if none
+}
+
+type dashboardState struct {
+	NumFloors   int
+	Id          int
+	Role        string
+	Master      int // -1 if unknown
+	Peers       []peers.ElevIdentity
+	FireRecall  bool
+	RecallFloor int
+	Traffic     string // The traffic mode: normal, upPeak or downPeak
+	TrafficFrom string // detected, schedule or api
+	Cars        []dashboardCar
+	HallCalls   []dashboardHallCall
+}
+
+// Records an event for the dashboard
+func (c *Client) recordEvent(text string) {
+	c.mutex_events.Lock()
+	defer c.mutex_events.Unlock()
+	c.eventSeq++
+	c.events = append(c.events, dashboardEvent{Seq: c.eventSeq, Time: time.Now(), Text: text})
+	if len(c.events) > dashboardEventsKept {
+		c.events = c.events[len(c.events)-dashboardEventsKept:]
+	}
+}
+
+// Logs a message of a component and records it as an event for the dashboard
+func (c *Client) logEvent(component string, format string, args ...interface{}) {
+	text := fmt.Sprintf(format, args...)
+	c.logger(component).Infof("%s", text)
+	c.recordEvent(text)
+}
+
+// Records the hall calls turned on and off by the master
+func (c *Client) logHallCalls(previous, current []Order) {
+	for _, order := range findUniqueOrders(previous, current) {
+		state := "off"
+		if orderInContainer(current, order) {
+			state = "on"
+		}
+		c.recordEvent(fmt.Sprintf("Hall call %s at floor %d is %s", directionName(order.Direction), order.Floor, state))
+	}
+}
+
+// Returns the events after seq
+func (c *Client) eventsSince(seq int) []dashboardEvent {
+	c.mutex_events.Lock()
+	defer c.mutex_events.Unlock()
+	events := []dashboardEvent{}
+	for _, event := range c.events {
+		if event.Seq > seq {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func directionName(direction OrderDirection) string {
+	if direction == up {
+		return "up"
+	}
+	return "down"
+}
+
+// The view of the cluster shown on the dashboard
+func (c *Client) dashboardSnapshot() dashboardState {
+	snapshot := dashboardState{NumFloors: numFloors, Id: c.id, Role: c.Role(), Master: -1, Cars: []dashboardCar{}, HallCalls: []dashboardHallCall{}}
+
+	c.mutex_peers.Lock()
+	snapshot.Peers = append([]peers.ElevIdentity{}, c.peers...)
+	c.mutex_peers.Unlock()
+	snapshot.FireRecall, snapshot.RecallFloor = c.fireRecallState()
+	snapshot.Traffic, snapshot.TrafficFrom, _ = c.trafficState()
+	for _, peer := range snapshot.Peers {
+		if peer.Role == "Master" {
+			snapshot.Master = peer.Id
+		}
+	}
+
+	c.mutex_backup.Lock()
+	states := c.backupStates
+	pending := append([]Order{}, c.pendingHallOrders...)
+	c.mutex_backup.Unlock()
+	c.mutex_confirmedHallOrders.Lock()
+	confirmed := append([]Order{}, c.confirmedHallOrders...)
+	c.mutex_confirmedHallOrders.Unlock()
+
+	c.mutex_state.Lock()
+	states[c.id] = c.latestState // Ours is the freshest
+	c.mutex_state.Unlock()
+
+	c.mutex_activeElevators.Lock()
+	for id, state := range states {
+		if state.Behavior == "Uninitialized" {
+			continue
+		}
+		snapshot.Cars = append(snapshot.Cars, dashboardCar{
+			Id:            id,
+			Active:        c.isElevatorActive(id),
+			InService:     !c.isOutOfService(id),
+			Behavior:      state.Behavior,
+			Mode:          state.Mode,
+			Floor:         state.Floor,
+			Direction:     state.Direction,
+			DoorOpen:      state.DoorOpen,
+			LocalRequests: state.LocalRequests,
+			ServedFloors:  state.ServedFloors,
+		})
+	}
+	c.mutex_activeElevators.Unlock()
+
+	// The hall panel: the calls lit by the master, assigned if they are in the queue of an elevator
+	for _, order := range confirmed {
+		call := dashboardHallCall{Floor: order.Floor, Direction: directionName(order.Direction), Status: "confirmed", Elevator: -1}
+		for id, state := range states {
+			if state.Behavior != "Uninitialized" && orderInContainer(state.LocalRequests, order) {
+				call.Status, call.Elevator = "assigned", id
+			}
+		}
+		snapshot.HallCalls = append(snapshot.HallCalls, call)
+	}
+	for _, order := range pending {
+		snapshot.HallCalls = append(snapshot.HallCalls, dashboardHallCall{Floor: order.Floor, Direction: directionName(order.Direction), Status: "unconfirmed", Elevator: -1})
+	}
+
+	return snapshot
+}
+
+func (c *Client) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(dashboardPage)
+}
+
+// The current view of the cluster, as JSON
+func (c *Client) handleDashboardState(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(c.dashboardSnapshot())
+}
+
+// Server-Sent Events: the view of the cluster ("state") every dashboardRate, and the new events ("log")
+func (c *Client) handleDashboardEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	ticker := time.NewTicker(dashboardRate)
+	defer ticker.Stop()
+	lastSeq := 0
+	for {
+		state, _ := json.Marshal(c.dashboardSnapshot())
+		fmt.Fprintf(w, "event: state\ndata: %s\n\n", state)
+		for _, event := range c.eventsSince(lastSeq) {
+			data, _ := json.Marshal(event)
+			fmt.Fprintf(w, "event: log\ndata: %s\n\n", data)
+			lastSeq = event.Seq
+		}
+		flusher.Flush()
+
+		select {
+		case <-ticker.C:
+		case <-r.Context().Done():
+			return
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
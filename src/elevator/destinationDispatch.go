@@ -0,0 +1,216 @@
+// This file contains the destination dispatch (see Config.DestinationDispatch): instead of pressing up or down, the
+// passengers enter their destination at the hall panel (POST /api/destinations). The master groups the passengers
+// going from the same floor to nearby floors, gives each group to one car as a hall order that carries their
+// destinations (Order.Destinations) and tells the hall panels which car to take (DestinationAssignedMsg). Once the
+// car has picked the group up, the destinations become its cab orders
+package elevator
+
+import (
+	"context"
+	"time"
+)
+
+// Returns a new destination call entered at our hall
+func (c *Client) destinationCall(floor, destination int) DestinationCallMsg {
+	return DestinationCallMsg{Id: c.nextOrderId(), Origin: c.id, Floor: floor, Destination: destination}
+}
+
+// Sends a destination call to the master (see handleButtonPress and handleAPIDestination)
+func (c *Client) enterDestination(call DestinationCallMsg) {
+	c.logger(logDriver).Infof("Destination %d entered at floor %d (%s)", call.Destination, call.Floor, call.Id)
+	select {
+	case c.destinationCallTx <- call:
+	case <-c.ctx.Done():
+	}
+}
+
+// Returns a channel on which the car taking a destination call is sent, once the master chose it (-1 if no car can
+// take it)
+func (c *Client) waitForDestinationCar(id string) chan int {
+	car := make(chan int, 1)
+	c.mutex_destination.Lock()
+	c.destinationWaiters[id] = car
+	c.mutex_destination.Unlock()
+	return car
+}
+
+func (c *Client) stopWaitingForDestinationCar(id string) {
+	c.mutex_destination.Lock()
+	delete(c.destinationWaiters, id)
+	c.mutex_destination.Unlock()
+}
+
+// Shows the car taking each destination call entered at our hall (the log and the API request waiting for it)
+func (c *Client) handleDestinationAssignments() {
+	for {
+		var a DestinationAssignedMsg
+		select {
+		case a = <-c.destinationAssignedRx:
+		case <-c.ctx.Done():
+			return
+		}
+
+		c.mutex_destination.Lock()
+		car, waiting := c.destinationWaiters[a.Id]
+		delete(c.destinationWaiters, a.Id)
+		c.mutex_destination.Unlock()
+
+		if waiting && a.Elevator == -1 {
+			c.logger(logDriver).Warnf("Floor %d to %v: no car can take it", a.Floor, a.Destinations)
+		} else if waiting {
+			c.logger(logDriver).Infof("Floor %d to %v: take car %d", a.Floor, a.Destinations, a.Elevator)
+		}
+		if waiting {
+			car <- a.Elevator // Buffered, answered once
+		}
+	}
+}
+
+// Called with the orders served at a floor (with the mutexes of attendToSpecificOrder): the passengers of the
+// groups picked up there are in, their destinations become our cab orders
+func (c *Client) pickUpDestinations(served []Order) {
+	pickedUp := false
+	for _, order := range served {
+		for _, destination := range order.Destinations {
+			if destination == order.Floor {
+				continue
+			}
+			cabOrder := c.newOrder(Order{Floor: destination, Direction: 0, OrderType: cab}).assignedTo(c.id)
+			c.addOrder(cabOrder)
+			c.turnOnCabLights(cabOrder)
+			pickedUp = true
+		}
+		if len(order.Destinations) > 0 {
+			c.logger(logFSM).Debugf("Picked up %d passengers at floor %d going to %v", len(order.Destinations), order.Floor, order.Destinations)
+		}
+	}
+	if pickedUp {
+		sortAllOrders(&c.elevatorOrders, c.d, c.posArray)
+	}
+}
+
+// The hall order that picks up the passenger of a destination call, in a new group
+func destinationOrder(call DestinationCallMsg) Order {
+	direction := up
+	if call.Destination < call.Floor {
+		direction = down
+	}
+	return Order{Floor: call.Floor, Direction: direction, OrderType: hall, Group: call.Id, Id: call.Id, Origin: call.Origin,
+		CreatedAt: time.Now(), Destinations: []int{call.Destination}}
+}
+
+// Leaves out the candidates that already pick up passengers at the floor of the order, going the same way, with
+// another hall order: a car picks up one group there at a time
+func withoutPickupAt(hallOrders map[orderKey]hallOrderRecord, candidates []int, order Order) []int {
+	busy := make(map[int]bool)
+	for key, record := range hallOrders {
+		if record.Status == assigned && key != order.key() && key.Floor == order.Floor && key.Direction == order.Direction {
+			busy[record.Elevator] = true
+		}
+	}
+	left := []int{}
+	for _, id := range candidates {
+		if !busy[id] {
+			left = append(left, id)
+		}
+	}
+	return left
+}
+
+// Returns the group (an assigned hall order of one of the candidates at the floor of the order, going the same way)
+// a passenger going to destination joins: the one whose destinations stay the closest together, if it is not full
+// and they stay within destinationGroupSpread floors. False if there is none
+func joinDestinationGroup(hallOrders map[orderKey]hallOrderRecord, candidates []int, order Order, destination int) (hallOrderRecord, bool) {
+	isCandidate := make(map[int]bool)
+	for _, id := range candidates {
+		isCandidate[id] = true
+	}
+
+	var best hallOrderRecord
+	bestSpread := -1
+	for key, record := range hallOrders {
+		if record.Status != assigned || !isCandidate[record.Elevator] || key.Floor != order.Floor || key.Direction != order.Direction ||
+			len(record.Order.Destinations) >= destinationGroupSize {
+			continue
+		}
+		lowest, highest := destination, destination
+		for _, floor := range record.Order.Destinations {
+			if floor < lowest {
+				lowest = floor
+			}
+			if floor > highest {
+				highest = floor
+			}
+		}
+		if spread := highest - lowest; spread <= destinationGroupSpread && (bestSpread == -1 || spread < bestSpread) {
+			best, bestSpread = record, spread
+		}
+	}
+	return best, bestSpread != -1
+}
+
+// Returns the candidate that takes a new group at the lowest cost: the one of calculateCost, plus a stop for the
+// destination if the car does not stop there already. -1 if there is no candidate
+func cheapestForDestination(allStates [numElev]ElevState, candidates []int, order Order, destination int) int {
+	best, bestCost := -1, 0.0
+	for _, id := range candidates {
+		state := allStates[id]
+		if state.Behavior == "Uninitialized" {
+			continue
+		}
+		cost := calculateCost(state, order) + destinationStopCost
+		for _, request := range state.LocalRequests {
+			stops := append([]int{request.Floor}, request.Destinations...)
+			for _, floor := range stops {
+				if floor == destination {
+					cost = calculateCost(state, order)
+				}
+			}
+		}
+		if best == -1 || cost < bestCost {
+			best, bestCost = id, cost
+		}
+	}
+	return best
+}
+
+// Sends a group to its car again with one more passenger (for the master), the car merges it with the one it has
+func (c *Client) regroupHallOrder(ctx context.Context, id int, order Order) bool {
+	c.mutex_backup.Lock()
+	requests := append([]Order{}, c.backupStates[id].LocalRequests...) // Shared with allStates
+	for i, request := range requests {
+		if request.SameButton(order) {
+			requests[i] = order
+		}
+	}
+	c.backupStates[id].LocalRequests = requests
+	c.mutex_backup.Unlock()
+
+	select {
+	case c.hallOrderTx <- HallOrderMsg{id, order}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Tells the hall panels which car takes a group, in answer to the destination call callId (for the master)
+func (c *Client) announceDestinationGroup(ctx context.Context, callId string, id int, order Order) bool {
+	c.logger(logMaster).Debugf("Group %s at floor %d going to %v taken by elevator %d", order.Group, order.Floor, order.Destinations, id)
+	select {
+	case c.destinationAssignedTx <- DestinationAssignedMsg{Id: callId, Floor: order.Floor, Destinations: order.Destinations, Elevator: id}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Tells the hall panel that no car takes the destination call callId (for the master), so that it does not wait for one
+func (c *Client) refuseDestinationCall(ctx context.Context, callId string, order Order) bool {
+	select {
+	case c.destinationAssignedTx <- DestinationAssignedMsg{Id: callId, Floor: order.Floor, Destinations: order.Destinations, Elevator: -1}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
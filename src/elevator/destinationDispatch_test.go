@@ -0,0 +1,147 @@
+package elevator
+
+import "testing"
+
+func TestJoinDestinationGroup(t *testing.T) {
+	// Returns the record of the group name picked up at floor going in direction, taken by elevator
+	groupAt := func(floor int, direction OrderDirection, name string, elevator int, status HallOrderStatus, destinations ...int) hallOrderRecord {
+		order := Order{Floor: floor, Direction: direction, OrderType: hall, Group: name, Destinations: destinations}
+		return hallOrderRecord{Order: order, Status: status, Elevator: elevator}
+	}
+	full := []int{}
+	for i := 0; i < destinationGroupSize; i++ {
+		full = append(full, 3)
+	}
+
+	tests := []struct {
+		name        string
+		groups      []hallOrderRecord
+		candidates  []int
+		destination int
+		want        string // The group joined, empty for none
+	}{
+		{
+			name:        "no groups",
+			candidates:  []int{0, 1, 2},
+			destination: 3,
+		},
+		{
+			name:        "joins the group at the floor going the same way",
+			groups:      []hallOrderRecord{groupAt(0, up, "a", 1, assigned, 2, 3)},
+			candidates:  []int{0, 1, 2},
+			destination: 3,
+			want:        "a",
+		},
+		{
+			name: "not the groups of another floor or direction",
+			groups: []hallOrderRecord{
+				groupAt(1, up, "a", 0, assigned, 3),
+				groupAt(0, down, "b", 1, assigned, 3),
+			},
+			candidates:  []int{0, 1, 2},
+			destination: 3,
+		},
+		{
+			name:        "not a group that is not assigned yet",
+			groups:      []hallOrderRecord{groupAt(0, up, "a", 1, confirmed, 3)},
+			candidates:  []int{0, 1, 2},
+			destination: 3,
+		},
+		{
+			name:        "not the group of a car that is not a candidate",
+			groups:      []hallOrderRecord{groupAt(0, up, "a", 1, assigned, 3)},
+			candidates:  []int{0, 2},
+			destination: 3,
+		},
+		{
+			name:        "not a full group",
+			groups:      []hallOrderRecord{groupAt(0, up, "a", 1, assigned, full...)},
+			candidates:  []int{0, 1, 2},
+			destination: 3,
+		},
+		{
+			name: "the group whose destinations stay the closest together",
+			groups: []hallOrderRecord{
+				groupAt(0, up, "a", 0, assigned, 1),
+				groupAt(0, up, "b", 1, assigned, 3),
+				groupAt(0, up, "c", 2, assigned, 2),
+			},
+			candidates:  []int{0, 1, 2},
+			destination: 3,
+			want:        "b",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hallOrders := make(map[orderKey]hallOrderRecord)
+			for _, record := range test.groups {
+				hallOrders[record.Order.key()] = record
+			}
+			order := Order{Floor: 0, Direction: up, OrderType: hall}
+			record, ok := joinDestinationGroup(hallOrders, test.candidates, order, test.destination)
+			if ok != (test.want != "") || (ok && record.Order.Group != test.want) {
+				t.Errorf("joinDestinationGroup() = %q, %v, want %q", record.Order.Group, ok, test.want)
+			}
+		})
+	}
+}
+
+func TestCheapestForDestination(t *testing.T) {
+	// Returns an idle car standing at floor with requests
+	idleAt := func(floor int, requests ...Order) ElevState {
+		return ElevState{Behavior: "idle", Floor: floor, Direction: "stop", LocalRequests: requests}
+	}
+	group := Order{Floor: 2, Direction: up, OrderType: hall, Group: "a", Destinations: []int{3}}
+
+	tests := []struct {
+		name       string
+		allStates  [numElev]ElevState
+		candidates []int
+		want       int
+	}{
+		{
+			name:       "no candidates",
+			allStates:  [numElev]ElevState{idleAt(0), idleAt(1), idleAt(2)},
+			candidates: nil,
+			want:       -1,
+		},
+		{
+			name:       "the nearest car",
+			allStates:  [numElev]ElevState{idleAt(1), idleAt(2), idleAt(3)},
+			candidates: []int{0, 1, 2},
+			want:       0,
+		},
+		{
+			name:       "an uninitialized car is left out",
+			allStates:  [numElev]ElevState{{Behavior: "Uninitialized"}, idleAt(2), idleAt(3)},
+			candidates: []int{0, 1, 2},
+			want:       1,
+		},
+		{
+			name:       "only the candidates",
+			allStates:  [numElev]ElevState{idleAt(1), idleAt(2), idleAt(3)},
+			candidates: []int{2},
+			want:       2,
+		},
+		{
+			name:       "a car with a cab order to the destination saves a stop",
+			allStates:  [numElev]ElevState{idleAt(1), idleAt(2, cabOrderAt(3)), idleAt(3)},
+			candidates: []int{0, 1, 2},
+			want:       1,
+		},
+		{
+			name:       "a car with a group going to the destination saves a stop",
+			allStates:  [numElev]ElevState{idleAt(1), idleAt(2, group), idleAt(3)},
+			candidates: []int{0, 1, 2},
+			want:       1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			order := Order{Floor: 0, Direction: up, OrderType: hall}
+			if got := cheapestForDestination(test.allStates, test.candidates, order, 3); got != test.want {
+				t.Errorf("cheapestForDestination() = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,302 @@
+// This file contains the fire service of the client. Phase I (fire recall) is building-wide: the master cancels
+// every hall call and ignores the hall buttons, and every car drops its orders, goes non-stop to the recall floor
+// and parks there with its door open, until the recall is reset.
+// Phase II (firefighter operation) is per car: once recalled, a car can be driven by a firefighter from its panel,
+// with cab calls only and a door that opens only while its button is held
+package elevator
+
+import (
+	"Driver-go/elevio"
+	"errors"
+	"time"
+)
+
+// FireRecallSwitch is the fire recall key switch of a car. The drivers that have one implement it, the client then
+// sends its positions to the master
+type FireRecallSwitch interface {
+	PollFireRecallSwitch(receiver chan<- bool)
+}
+
+// FirefighterPanel is the firefighter key switch (Phase II) and the door button of a car, for the drivers that have
+// them. The door button sends true when pressed and false when released
+type FirefighterPanel interface {
+	PollFirefighterSwitch(receiver chan<- bool)
+	PollDoorButton(receiver chan<- bool)
+}
+
+// Returns the mode of the car
+func (c *Client) currentMode() string {
+	c.mutex_mode.Lock()
+	defer c.mutex_mode.Unlock()
+	return c.mode
+}
+
+// Sets the mode of the car and keeps it in our state
+func (c *Client) setMode(mode string) {
+	c.mutex_mode.Lock()
+	c.mode = mode
+	c.mutex_mode.Unlock()
+	c.mutex_state.Lock()
+	c.latestState.Mode = mode
+	c.mutex_state.Unlock()
+}
+
+// Tells whether the car takes new orders (hall and cab)
+func (c *Client) takesOrders() bool {
+	return c.currentMode() == modeNormal
+}
+
+// Tells whether the car takes cab calls (the firefighter operation and the independent service only take those)
+func (c *Client) takesCabCalls() bool {
+	mode := c.currentMode()
+	return mode == modeNormal || mode == modeFirefighter || mode == modeIndependent
+}
+
+// Tells whether the car is in one of the modes of the fire service
+func (c *Client) inFireService() bool {
+	mode := c.currentMode()
+	return mode == modeFireRecall || mode == modeFirefighter
+}
+
+// Returns the fire recall of the building and its floor
+func (c *Client) fireRecallState() (bool, int) {
+	c.mutex_mode.Lock()
+	defer c.mutex_mode.Unlock()
+	return c.fireRecall, c.recallFloor
+}
+
+func (c *Client) isFireRecall() bool {
+	active, _ := c.fireRecallState()
+	return active
+}
+
+func (c *Client) setFireRecallState(active bool, floor int) {
+	c.mutex_mode.Lock()
+	defer c.mutex_mode.Unlock()
+	c.fireRecall, c.recallFloor = active, floor
+}
+
+// Applies the fire recall broadcast by the master (see handleHallLights) to the car
+func (c *Client) applyFireRecall(active bool, floor int) {
+	c.setFireRecallState(active, floor)
+	mode := c.currentMode()
+	switch {
+	case active && (mode == modeNormal || mode == modeIndependent): // The independent service is recalled too
+		c.startFireRecall(floor)
+	case !active && mode == modeFireRecall:
+		c.endFireRecall()
+	}
+}
+
+// Drops all our orders and sends the car to the recall floor. It does not stop on the way
+func (c *Client) startFireRecall(floor int) {
+	c.setMode(modeFireRecall)
+
+	lockMutexes(&c.mutex_elevatorOrders, &c.mutex_d, &c.mutex_posArray)
+	cleared := len(c.elevatorOrders)
+	c.elevatorOrders = []Order{}
+
+	// Update & send the new state of the elevator to the master
+	c.updateState(c.lastFloor)
+	c.singleStateTx <- StateMsg{c.id, c.latestState}
+	unlockMutexes(&c.mutex_elevatorOrders, &c.mutex_d, &c.mutex_posArray)
+
+	c.turnOffAllLights()
+	c.logEvent(logFSM, "Fire recall: %d orders dropped, going to floor %d", cleared, floor)
+
+	// The recall floor is not one of our orders, attendToSpecificOrder parks the car there (see parkForFireRecall)
+	c.drv_newOrder <- Order{Floor: floor, OrderType: cab, Id: "fire-recall"}
+}
+
+// Called by attendToSpecificOrder once the car has stopped at the recall floor: the door stays open
+func (c *Client) parkForFireRecall(floor int) {
+	c.updateState(floor)
+	c.singleStateTx <- StateMsg{c.id, c.latestState}
+	c.setDoorOpen(true)
+	c.logEvent(logFSM, "Fire recall: parked at floor %d with the door open", floor)
+}
+
+// Back to normal once the recall is reset: the door closes and the car takes orders again
+func (c *Client) endFireRecall() {
+	c.setMode(modeNormal)
+	c.setDoorOpen(false)
+
+	lockMutexes(&c.mutex_elevatorOrders, &c.mutex_d, &c.mutex_posArray)
+	c.updateState(c.lastFloor)
+	c.singleStateTx <- StateMsg{c.id, c.latestState}
+	unlockMutexes(&c.mutex_elevatorOrders, &c.mutex_d, &c.mutex_posArray)
+
+	c.logEvent(logFSM, "Fire recall reset, back to normal")
+}
+
+// Turns the fire recall of the building on or off (for the master)
+func (c *Client) switchFireRecall(hallOrders map[orderKey]hallOrderRecord, active bool) {
+	if current, _ := c.fireRecallState(); current == active {
+		return
+	}
+	c.setFireRecallState(active, c.fireRecallFloor)
+	if !active {
+		c.logEvent(logMaster, "Fire recall reset")
+		return
+	}
+
+	// The hall calls are cancelled (their lights go off with the next HallLightsMsg), the cars drop their orders
+	for key := range hallOrders {
+		delete(hallOrders, key)
+	}
+	c.logEvent(logMaster, "Fire recall to floor %d", c.fireRecallFloor)
+}
+
+// Sends the fire recall to the master until it applies it. Returns an error if it did not in time
+func (c *Client) requestFireRecall(active bool) error {
+	timeout := time.After(apiTimeout)
+	for {
+		if current, _ := c.fireRecallState(); current == active {
+			return nil
+		}
+		select {
+		case c.fireRecallTx <- FireRecallMsg{From: c.id, Active: active}:
+		case <-c.ctx.Done():
+			return c.ctx.Err()
+		}
+
+		select {
+		case <-time.After(resendRateFireRecall):
+		case <-timeout:
+			return errors.New("The master did not apply the fire recall")
+		case <-c.ctx.Done():
+			return c.ctx.Err()
+		}
+	}
+}
+
+func (c *Client) handleFireRecallSwitch(recallSwitch FireRecallSwitch) {
+	positions := make(chan bool)
+	go recallSwitch.PollFireRecallSwitch(positions)
+	for {
+		select {
+		case on := <-positions:
+			if err := c.requestFireRecall(on); err != nil {
+				c.logger(logDriver).Errorf("Fire recall switch: %v", err)
+			}
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// Section_START -- Phase II
+
+// Turns the firefighter operation of the car on (only once it is recalled) or off. When it is turned off, the car
+// goes back to the recall floor, or back to normal if the recall was reset in the meantime
+func (c *Client) setFirefighter(on bool) error {
+	mode := c.currentMode()
+	switch {
+	case on == (mode == modeFirefighter):
+		return nil
+	case on && mode != modeFireRecall:
+		return errors.New("The firefighter operation needs the fire recall")
+	case on:
+		c.setMode(modeFirefighter)
+		c.setDoorOpen(false) // It now only opens while the door button is held
+
+		lockMutexes(&c.mutex_elevatorOrders, &c.mutex_d, &c.mutex_posArray)
+		c.updateState(c.lastFloor)
+		c.singleStateTx <- StateMsg{c.id, c.latestState}
+		unlockMutexes(&c.mutex_elevatorOrders, &c.mutex_d, &c.mutex_posArray)
+
+		c.logEvent(logFSM, "Firefighter operation: the car is driven from its panel")
+		return nil
+	}
+
+	c.mutex_mode.Lock()
+	c.doorHeld = false
+	c.mutex_mode.Unlock()
+	c.logEvent(logFSM, "Firefighter operation turned off")
+	if active, floor := c.fireRecallState(); active {
+		c.startFireRecall(floor) // Drops the cab calls left
+		return nil
+	}
+
+	lockMutexes(&c.mutex_elevatorOrders, &c.mutex_d, &c.mutex_posArray)
+	c.elevatorOrders = []Order{}
+	unlockMutexes(&c.mutex_elevatorOrders, &c.mutex_d, &c.mutex_posArray)
+	c.turnOffAllLights()
+	c.endFireRecall()
+	return nil
+}
+
+func (c *Client) isDoorHeld() bool {
+	c.mutex_mode.Lock()
+	defer c.mutex_mode.Unlock()
+	return c.doorHeld
+}
+
+// The door button of the firefighter operation: the door opens while it is held, if the car is stopped at a floor,
+// and closes when it is released. The car does not move while it is held
+func (c *Client) pressDoorButton(held bool) error {
+	if c.currentMode() != modeFirefighter {
+		return errors.New("The door button only works in firefighter operation")
+	}
+	lockMutexes(&c.mutex_d, &c.mutex_posArray)
+	position := c.extractPos()
+	stopped := c.d == elevio.MD_Stop && position == float32(int(position))
+	unlockMutexes(&c.mutex_d, &c.mutex_posArray)
+	if held && !stopped {
+		return errors.New("The car is not stopped at a floor")
+	}
+
+	c.mutex_mode.Lock()
+	c.doorHeld = held
+	c.mutex_mode.Unlock()
+	c.setDoorOpen(held)
+	if held {
+		return nil
+	}
+
+	// The cab calls registered while the door was open can now be served
+	c.mutex_elevatorOrders.Lock()
+	next := append([]Order{}, c.elevatorOrders...)
+	c.mutex_elevatorOrders.Unlock()
+	if len(next) > 0 {
+		c.drv_newOrder <- next[0]
+	}
+	return nil
+}
+
+// Called by attendToSpecificOrder (with its mutexes) once the car has stopped at a cab call in firefighter
+// operation: the door stays closed, and the other cab calls are cancelled
+func (c *Client) stopForFirefighter(floor int) {
+	arrivedAt := time.Now()
+	served := c.popOrders()
+	cancelled := len(c.elevatorOrders)
+	c.elevatorOrders = []Order{}
+	c.updateState(floor)
+	c.singleStateTx <- StateMsg{c.id, c.latestState}
+	c.localStatesForCabOrders <- StateMsg{c.id, c.latestState} // Turns off the cab lights
+	c.reportServedOrders(served, arrivedAt, time.Time{})
+	c.logEvent(logFSM, "Firefighter operation: stopped at floor %d, %d cab calls cancelled", floor, cancelled)
+}
+
+func (c *Client) handleFirefighterPanel(panel FirefighterPanel) {
+	switches := make(chan bool)
+	doorButton := make(chan bool)
+	go panel.PollFirefighterSwitch(switches)
+	go panel.PollDoorButton(doorButton)
+	for {
+		select {
+		case on := <-switches:
+			if err := c.setFirefighter(on); err != nil {
+				c.logger(logDriver).Warnf("Firefighter switch: %v", err)
+			}
+		case held := <-doorButton:
+			if err := c.pressDoorButton(held); err != nil {
+				c.logger(logDriver).Debugf("Door button: %v", err)
+			}
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// Section_END -- Phase II
@@ -0,0 +1,188 @@
+// This file contains the declaration of all constants shared by the client
+// The state of a client lives in the Client struct (see client.go)
+package elevator
+
+import (
+	"time"
+)
+
+const numFloors = 4 // Number of floors
+const numElev = 3   // Number of elevators
+
+const NumFloors = numFloors // Number of floors, for the drivers created outside of this package
+const NumElev = numElev     // Number of elevators, for the tools that decode the messages of the cluster (elevctl)
+
+const ( // Ports
+	HallOrder_PORT           = 16120 + iota // Send hall orders (slave <-> master)
+	HallOrderRawBTN_PORT                    // Send hall orders (raw button presses)
+	SingleElevatorState_PORT                // Send the state of a single elevator (master <-> slave)
+	AllStates_PORT                          // Send the states of all elevators (master <-> primary backup)
+	PeerChannel_PORT                        // Peer channel update port (all)
+	BackupStates_PORT                       // Unused (the new master starts from its own copy of the states), kept so the other ports do not move
+	HallOrderCompleted_PORT                 // Hall order completed port (slave <-> master)
+	ActiveElevators_PORT                    // Active elevators port (all)
+	RetrieveCabOrders_PORT                  // Retrieve cab orders port (slave <-> master)
+	AskForCabOrders_PORT                    // Ask for cab orders port (master <-> slave)
+	MissingElev_PORT                        // Missing elevator port (all)
+	AskForMissingInfo_PORT                  // Ask for missing info port (all)
+	SpamFromMaster_PORT                     // Spam port (all)
+	SpamFromSlave_PORT                      // Spam port (all)
+	Leave_PORT                              // Graceful shutdown port (all)
+	Rejoin_PORT                             // Rejoin after a network partition port (slave <-> master)
+	HallLights_PORT                         // Hall lights consistency port (slave <-> master)
+	OrderServed_PORT                        // Served orders statistics port (slave -> master)
+	CancelOrder_PORT                        // Cancelled orders port (master -> slave)
+	FireRecall_PORT                         // Fire recall switch port (slave -> master)
+	Inspection_PORT                         // Inspection commands port (elevctl <-> slave)
+	Park_PORT                               // Parking of the idle cars port (master -> slave)
+	DestinationCall_PORT                    // Destinations entered at the hall port (slave -> master)
+	DestinationAssigned_PORT                // Cars assigned to the destinations port (master -> slave)
+)
+
+// PortNames names every port above, for the tools that record the traffic of the cluster (elevctl record)
+var PortNames = map[int]string{
+	HallOrder_PORT:           "HallOrder",
+	HallOrderRawBTN_PORT:     "HallOrderRawBTN",
+	SingleElevatorState_PORT: "SingleElevatorState",
+	AllStates_PORT:           "AllStates",
+	PeerChannel_PORT:         "PeerChannel",
+	BackupStates_PORT:        "BackupStates",
+	HallOrderCompleted_PORT:  "HallOrderCompleted",
+	ActiveElevators_PORT:     "ActiveElevators",
+	RetrieveCabOrders_PORT:   "RetrieveCabOrders",
+	AskForCabOrders_PORT:     "AskForCabOrders",
+	MissingElev_PORT:         "MissingElev",
+	AskForMissingInfo_PORT:   "AskForMissingInfo",
+	SpamFromMaster_PORT:      "SpamFromMaster",
+	SpamFromSlave_PORT:       "SpamFromSlave",
+	Leave_PORT:               "Leave",
+	Rejoin_PORT:              "Rejoin",
+	HallLights_PORT:          "HallLights",
+	OrderServed_PORT:         "OrderServed",
+	CancelOrder_PORT:         "CancelOrder",
+	FireRecall_PORT:          "FireRecall",
+	Inspection_PORT:          "Inspection",
+	Park_PORT:                "Park",
+	DestinationCall_PORT:     "DestinationCall",
+	DestinationAssigned_PORT: "DestinationAssigned",
+}
+
+const (
+	BT_HallUp   ButtonType = 0
+	BT_HallDown ButtonType = 1
+	BT_Cab      ButtonType = 2
+)
+
+const (
+	up   OrderDirection = 1
+	down OrderDirection = -1
+)
+
+const (
+	hall OrderType = 0
+	cab  OrderType = 1
+)
+
+// unconfirmed -> confirmed -> assigned -> served. A hall order is served once it is removed from the queue of its
+// elevator, and then the master forgets it (and its lights go off)
+const (
+	unconfirmed HallOrderStatus = iota // Received by the master only
+	confirmed                          // Known by the master and the PrimaryBackup, the lights are on
+	assigned                           // Sent to an elevator
+)
+
+// Variables for the MotorStop
+const timerHallOrder time.Duration = 3 * time.Second    // Assuming 3 seconds for the timer
+const pollRateMotorStop time.Duration = 3 * time.Second // The rate at which we check for power shortage
+
+// Variables for the hall order watchdog
+const watchdogRate time.Duration = 500 * time.Millisecond             // The rate at which the master checks the assigned hall orders
+const travelTimeBetweenFloors time.Duration = 2500 * time.Millisecond // The time the car needs to move by one floor
+const doorOpenTime time.Duration = 3 * time.Second                    // The time the door stays open at each stop
+const defaultHallOrderTimeoutFactor float64 = 3                       // Re-assign a hall order after this multiple of its estimated service time
+const maxOrderHistory = 4                                             // The elevators kept in Order.History, so that the messages stay small
+
+// Variables for the graceful shutdown
+const resendRateLeave time.Duration = 50 * time.Millisecond // The rate at which we send the LeaveMsg until it is acknowledged
+
+// Variables for the hall lights
+const hallLightsRate time.Duration = 100 * time.Millisecond // The rate at which the master broadcasts the confirmed hall orders
+
+// Variables for the rejoin after a network partition
+const resendRateRejoin time.Duration = 50 * time.Millisecond // The rate at which we send the RejoinMsg until it is acknowledged
+const rejoinElectionDelay time.Duration = 1 * time.Second    // How long we wait for a master to appear before electing one
+
+// The components of the logs, their levels can be set one by one (see Config.Log)
+const (
+	logClient = "client" // Start and shutdown of the client
+	logDriver = "driver" // Buttons, obstruction and stop button
+	logFSM    = "fsm"    // The car and its orders
+	logMaster = "master" // The master routine
+	logBackup = "backup" // The PrimaryBackup routine
+	logPeers  = "peers"  // Peers, roles, shutdowns and rejoins
+	logHTTP   = "http"   // Metrics, dashboard and API
+)
+
+// The modes of a car (ElevState.Mode)
+const (
+	modeNormal      = "normal"      // Serves the hall and cab orders
+	modeFireRecall  = "fireRecall"  // Fire service Phase I: goes non-stop to the recall floor and parks there with the door open
+	modeFirefighter = "firefighter" // Fire service Phase II: driven from the car by a firefighter, cab calls only
+	modeIndependent = "independent" // Out of the group dispatch, driven with cab calls (see independentService.go)
+	modeInspection  = "inspection"  // Maintenance: no orders, jogged floor by floor (see inspection.go)
+)
+
+// Variables for the fire service
+const resendRateFireRecall time.Duration = 100 * time.Millisecond // The rate at which we send the FireRecallMsg until the master applies it
+
+// The traffic modes of the building (see traffic.go)
+const (
+	trafficNormal   = "normal"   // The cars wait where they stopped
+	trafficUpPeak   = "upPeak"   // Most calls are going up from the lobby: the idle cars wait at the lobby
+	trafficDownPeak = "downPeak" // Most calls are going down to the lobby: the idle cars wait spread over the upper floors
+)
+
+// Variables for the traffic detection
+const trafficRate time.Duration = 1 * time.Second   // The rate at which the master updates the traffic mode and parks the idle cars
+const trafficWindow time.Duration = 5 * time.Minute // The hall calls of this sliding window are counted
+const trafficMinCalls = 8                           // Fewer calls in the window is normal traffic
+const trafficPeakShare float64 = 0.6                // A peak starts when this share of the calls goes up from the lobby (or down)
+const trafficEndShare float64 = 0.4                 // A peak ends when the share falls below this one
+
+// The parking policies of the idle cars in normal traffic (Config.ParkingPolicy, see parking.go)
+const (
+	parkingNone   = "none"   // The cars wait where they stopped
+	parkingLobby  = "lobby"  // The cars return to the lobby
+	parkingZones  = "zones"  // The floors are split into one zone per car, a car waits in the middle of its zone
+	parkingDemand = "demand" // The cars wait at the floors with the most hall calls of the traffic window
+)
+
+const defaultParkingDelay time.Duration = 20 * time.Second // How long a car stays idle before it is parked
+
+// Variables for the served floors (see zoning.go)
+const (
+	refusedCallBlinks    = 3                      // How many times the lamp of a refused cab call blinks
+	refusedCallBlinkRate = 150 * time.Millisecond // How long the lamp stays on, then off, at each blink
+)
+
+// Variables for the destination dispatch (see destinationDispatch.go)
+const (
+	destinationGroupSize   = 8   // The most passengers a car picks up in one group
+	destinationGroupSpread = 2   // The most floors between the destinations of a group
+	destinationStopCost    = 1.0 // The cost of one more stop of a car for a new destination, on top of calculateCost
+)
+
+// Variables for the control API
+const apiTimeout time.Duration = 2 * time.Second // How long a request waits for the master to handle it
+
+// Variables for the dashboard
+const dashboardRate time.Duration = 250 * time.Millisecond // The rate at which the view of the cluster is pushed to the dashboard
+const dashboardEventsKept = 200                            // The number of events kept for the dashboard
+
+const statsDuplicateWindow time.Duration = 1 * time.Minute // How long the id of a recorded order is kept, to skip the reports received twice
+
+// The upper bounds of the buckets of the wait time and journey time histograms (see statistics.go)
+var statsBuckets = []time.Duration{
+	1 * time.Second, 2 * time.Second, 5 * time.Second, 10 * time.Second, 20 * time.Second,
+	30 * time.Second, 60 * time.Second, 120 * time.Second, 300 * time.Second,
+}
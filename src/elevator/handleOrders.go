@@ -1,4 +1,4 @@
-package main
+package elevator
 
 import (
 	"Driver-go/elevio"
@@ -48,7 +48,7 @@ func findLowestOrders(elevatorOrders []Order) []Order {
 
 func orderInContainer(order_slice []Order, order_ Order) bool {
 	for _, v := range order_slice {
-		if v == order_ {
+		if v.SameButton(order_) {
 			return true
 		}
 	}
@@ -56,126 +56,206 @@ func orderInContainer(order_slice []Order, order_ Order) bool {
 }
 
 // This function will attend to the current order, it
-func attendToSpecificOrder(d *elevio.MotorDirection, consumer2drv_floors chan int, drv_newOrder chan Order, drv_DirectionChange chan elevio.MotorDirection,
-	singleStateTx chan StateMsg, id int, localStatesForCabOrders chan StateMsg) {
-	current_order := Order{0, -1, 0}
+func (c *Client) attendToSpecificOrder(consumer2drv_floors chan int) {
+	id := c.id
+	current_order := Order{Floor: 0, Direction: -1, OrderType: 0}
 	for {
 		select {
 		case a := <-consumer2drv_floors: // Triggers when we arrive at a new floor
-			lockMutexes(&mutex_d, &mutex_elevatorOrders, &mutex_posArray)
+			if c.isShuttingDown() { // Shutdown is driving the elevator
+				continue
+			}
+			lockMutexes(&c.mutex_d, &c.mutex_elevatorOrders, &c.mutex_posArray)
+			if c.currentMode() == modeInspection { // The car is jogged, it stops at every floor
+				c.jogFloorReached(a)
+				unlockMutexes(&c.mutex_d, &c.mutex_elevatorOrders, &c.mutex_posArray)
+				continue
+			}
 			if a == current_order.Floor { // Check if our new floor is equal to the floor of the order
 				// Set direction to stop and delete relevant orders from elevatorOrders
 
-				*d = elevio.MD_Stop
-				elevio.SetMotorDirection(*d)
+				c.d = elevio.MD_Stop
+				c.driver.SetMotorDirection(c.d)
+
+				if c.currentMode() == modeFireRecall { // No stop on the way, the car parks at the recall floor only
+					if _, recallFloor := c.fireRecallState(); a == recallFloor {
+						c.parkForFireRecall(a)
+					}
+					unlockMutexes(&c.mutex_d, &c.mutex_elevatorOrders, &c.mutex_posArray)
+					continue
+				}
+				if c.currentMode() == modeFirefighter { // No door cycle, the firefighter opens it
+					c.stopForFirefighter(a)
+					unlockMutexes(&c.mutex_d, &c.mutex_elevatorOrders, &c.mutex_posArray)
+					continue
+				}
+				if c.currentMode() == modeIndependent { // The door stays open until a cab call is pressed
+					c.stopForIndependentService(a)
+					unlockMutexes(&c.mutex_d, &c.mutex_elevatorOrders, &c.mutex_posArray)
+					continue
+				}
+				if current_order.Id == parkOrderId { // No door cycle, the car waits there for its next order
+					c.parked(a)
+					unlockMutexes(&c.mutex_d, &c.mutex_elevatorOrders, &c.mutex_posArray)
+					continue
+				}
 
 				// Clear the cab lights for this order, (the removal of hallOrders is sent through the MasterRoutine and back to all single elevators)
 
-				PopOrders()
-				updateState(d, current_order.Floor, elevatorOrders, &latestState)
-				singleStateTx <- StateMsg{id, latestState}
-				localStatesForCabOrders <- StateMsg{id, latestState}
-
-				mutex_waiting.Lock()
-				if !isWaiting {
-					isWaiting = true
-					mutex_waiting.Unlock()
-					elevio.SetDoorOpenLamp(true)
-					StopBlocker(3000 * time.Millisecond)
-					elevio.SetDoorOpenLamp(false)
-					mutex_waiting.Lock()
-					isWaiting = false
+				arrivedAt := time.Now()
+				served := c.popOrders()
+				c.pickUpDestinations(served) // See destinationDispatch.go
+				c.updateState(current_order.Floor)
+				c.singleStateTx <- StateMsg{id, c.latestState}
+				c.localStatesForCabOrders <- StateMsg{id, c.latestState}
+
+				var doorClosedAt time.Time // Unknown if the door was already open
+				c.mutex_waiting.Lock()
+				if !c.isWaiting {
+					c.isWaiting = true
+					c.mutex_waiting.Unlock()
+					c.setDoorOpen(true)
+					c.stopBlocker(3000 * time.Millisecond)
+					c.setDoorOpen(false)
+					doorClosedAt = time.Now()
+					c.mutex_waiting.Lock()
+					c.isWaiting = false
 				}
-				mutex_waiting.Unlock()
+				c.mutex_waiting.Unlock()
+				c.reportServedOrders(served, arrivedAt, doorClosedAt)
 
 				// After deleting the relevant orders at our floor => find, if any, the next currentOrder
-				if len(elevatorOrders) != 0 {
-					current_order = elevatorOrders[0]
-					prev_direction := *d
-					changeDirBasedOnCurrentOrder(d, current_order, float32(a))
-					new_direction := *d
+				if len(c.elevatorOrders) != 0 {
+					current_order = c.elevatorOrders[0]
+					prev_direction := c.d
+					changeDirBasedOnCurrentOrder(&c.d, current_order, float32(a))
+					new_direction := c.d
 
-					elevio.SetMotorDirection(*d)
+					c.driver.SetMotorDirection(c.d)
 
 					// Communicate with trackPosition if our direction was altered
-					unlockMutexes(&mutex_d, &mutex_posArray)
+					unlockMutexes(&c.mutex_d, &c.mutex_posArray)
 					if prev_direction != new_direction {
-						drv_DirectionChange <- new_direction
+						c.drv_DirectionChange <- new_direction
 					}
-					lockMutexes(&mutex_d, &mutex_posArray)
-				} else {
-					//turnOffAllLights()
+					lockMutexes(&c.mutex_d, &c.mutex_posArray)
 				}
+				// Else the car is idle where it stopped, until the master parks it (see parking.go)
+			}
+			unlockMutexes(&c.mutex_d, &c.mutex_elevatorOrders, &c.mutex_posArray)
+		case a := <-c.drv_newOrder: // If we get a new order => update current order and see if we need to redirect our elevator
+			if c.isShuttingDown() { // Shutdown is driving the elevator
+				continue
 			}
-			unlockMutexes(&mutex_d, &mutex_elevatorOrders, &mutex_posArray)
-		case a := <-drv_newOrder: // If we get a new order => update current order and see if we need to redirect our elevator
-			lockMutexes(&mutex_posArray)
+			if a.Id == parkOrderId && c.hasOrders() { // The car got an order since it was parked, it goes on with it
+				continue
+			}
+			lockMutexes(&c.mutex_posArray)
 
 			current_order = a
-			current_position := extractPos()
+			current_position := c.extractPos()
 			switch {
-			// Case 1: HandleOrders sent a new Order and it is at the same floor
-			case *d == elevio.MD_Stop && current_position == float32(current_order.Floor):
+			// The car is jogged in inspection, it has no orders
+			case c.currentMode() == modeInspection:
+
+			// Case 0: the fire recall sends the car to the floor where it already is
+			case c.d == elevio.MD_Stop && current_position == float32(current_order.Floor) && c.currentMode() == modeFireRecall:
+
+				lockMutexes(&c.mutex_d, &c.mutex_elevatorOrders)
+				c.parkForFireRecall(current_order.Floor)
+				unlockMutexes(&c.mutex_d, &c.mutex_elevatorOrders)
+
+			// Case 0b: a cab call of the firefighter at the floor where the car is
+			case c.d == elevio.MD_Stop && current_position == float32(current_order.Floor) && c.currentMode() == modeFirefighter:
 
-				lockMutexes(&mutex_d, &mutex_elevatorOrders)
-				PopOrders()
-				updateState(d, current_order.Floor, elevatorOrders, &latestState)
-				singleStateTx <- StateMsg{id, latestState}
-				localStatesForCabOrders <- StateMsg{id, latestState}
-				unlockMutexes(&mutex_d, &mutex_elevatorOrders)
+				lockMutexes(&c.mutex_d, &c.mutex_elevatorOrders)
+				c.stopForFirefighter(current_order.Floor)
+				unlockMutexes(&c.mutex_d, &c.mutex_elevatorOrders)
 
-				elevio.SetDoorOpenLamp(true)
-				StopBlocker(3000 * time.Millisecond)
-				elevio.SetDoorOpenLamp(false)
+			// Case 0c: a cab call in independent service at the floor where the car is
+			case c.d == elevio.MD_Stop && current_position == float32(current_order.Floor) && c.currentMode() == modeIndependent:
+
+				lockMutexes(&c.mutex_d, &c.mutex_elevatorOrders)
+				c.stopForIndependentService(current_order.Floor)
+				unlockMutexes(&c.mutex_d, &c.mutex_elevatorOrders)
+
+			// Case 0d: the firefighter holds the door open, the car waits (pressDoorButton sends the order again)
+			case c.currentMode() == modeFirefighter && c.isDoorHeld():
+
+			// Case 0e: the car is parked at the floor where it already is
+			case c.d == elevio.MD_Stop && current_position == float32(current_order.Floor) && current_order.Id == parkOrderId:
+
+			// Case 1: HandleOrders sent a new Order and it is at the same floor
+			case c.d == elevio.MD_Stop && current_position == float32(current_order.Floor):
+
+				arrivedAt := time.Now()
+				lockMutexes(&c.mutex_d, &c.mutex_elevatorOrders)
+				served := c.popOrders()
+				c.pickUpDestinations(served) // See destinationDispatch.go
+				c.updateState(current_order.Floor)
+				c.singleStateTx <- StateMsg{id, c.latestState}
+				c.localStatesForCabOrders <- StateMsg{id, c.latestState}
+				unlockMutexes(&c.mutex_d, &c.mutex_elevatorOrders)
+
+				// The position is not held during the door cycle: a new order (e.g. one more destination group) locks it
+				// after mutex_d, which we take again below
+				unlockMutexes(&c.mutex_posArray)
+				c.setDoorOpen(true)
+				c.stopBlocker(3000 * time.Millisecond)
+				c.setDoorOpen(false)
+				lockMutexes(&c.mutex_posArray)
+				c.reportServedOrders(served, arrivedAt, time.Now())
 
 				// After deleting the relevant orders at our floor => find, if any, find the next currentOrder
-				if len(elevatorOrders) != 0 {
-					current_order = elevatorOrders[0]
-					prev_direction := *d
+				if len(c.elevatorOrders) != 0 {
+					current_order = c.elevatorOrders[0]
+					prev_direction := c.d
 
-					mutex_d.Lock()
-					changeDirBasedOnCurrentOrder(d, current_order, float32(current_order.Floor))
-					mutex_d.Unlock()
+					c.mutex_d.Lock()
+					changeDirBasedOnCurrentOrder(&c.d, current_order, float32(current_order.Floor))
+					c.mutex_d.Unlock()
 
-					new_direction := *d
+					new_direction := c.d
 
-					elevio.SetMotorDirection(*d)
+					c.driver.SetMotorDirection(c.d)
 
 					// Communicate with trackPosition if our direction was altered
-					unlockMutexes(&mutex_posArray)
+					unlockMutexes(&c.mutex_posArray)
 					if prev_direction != new_direction {
-						drv_DirectionChange <- new_direction
+						c.drv_DirectionChange <- new_direction
 					}
-					lockMutexes(&mutex_posArray)
+					lockMutexes(&c.mutex_posArray)
 				} else {
-					turnOffAllLights()
+					c.turnOffAllLights()
 				}
 
 				// Case 2: HandleOrders sent a new Order and it is at a different floor
 			case current_position != float32(current_order.Floor):
-				current_position := extractPos()
+				current_position := c.extractPos()
 
-				prev_direction := *d
+				prev_direction := c.d
 
-				mutex_d.Lock()
-				changeDirBasedOnCurrentOrder(d, current_order, current_position)
-				mutex_d.Unlock()
+				c.mutex_d.Lock()
+				changeDirBasedOnCurrentOrder(&c.d, current_order, current_position)
+				c.mutex_d.Unlock()
 
-				new_direction := *d
+				new_direction := c.d
 
-				elevio.SetDoorOpenLamp(false) // Just in case
+				c.setDoorOpen(false) // Just in case
 
-				elevio.SetMotorDirection(*d)
+				c.driver.SetMotorDirection(c.d)
 
 				// Communicate with trackPosition if our direction was altered
-				unlockMutexes(&mutex_posArray)
+				unlockMutexes(&c.mutex_posArray)
 				if prev_direction != new_direction {
-					drv_DirectionChange <- new_direction
+					c.drv_DirectionChange <- new_direction
 				}
-				lockMutexes(&mutex_posArray)
+				lockMutexes(&c.mutex_posArray)
 			}
 
-			unlockMutexes(&mutex_posArray)
+			unlockMutexes(&c.mutex_posArray)
+		case <-c.ctx.Done():
+			return
 		}
 	}
 }
@@ -198,7 +278,7 @@ func sortOrdersInDirection(elevatorOrders []Order, d elevio.MotorDirection, posA
 		// Find Direction based on cab order
 		num_cabOrdersAbove := 0
 		num_cabOrdersBelow := 0
-		closest := Order{100000, 1, 1}
+		closest := Order{Floor: 100000, Direction: 1, OrderType: 1}
 
 		for _, order := range elevatorOrders {
 			floor_order := float32(order.Floor)
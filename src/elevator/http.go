@@ -0,0 +1,59 @@
+// This file contains the optional HTTP server of the client: the metrics, the dashboard and the API can be served
+// on the same address or on different ones
+package elevator
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// Starts one HTTP server per configured address, until the client is stopped
+func (c *Client) serveHTTP() {
+	muxes := make(map[string]*http.ServeMux)
+	handle := func(addr, pattern string, handler http.HandlerFunc) {
+		if addr == "" {
+			return
+		}
+		if muxes[addr] == nil {
+			muxes[addr] = http.NewServeMux()
+		}
+		muxes[addr].HandleFunc(pattern, handler)
+	}
+
+	handle(c.metricsAddr, "/metrics", c.handleMetrics)
+	handle(c.dashboardAddr, "/", c.handleDashboard)
+	handle(c.dashboardAddr, "/state", c.handleDashboardState)
+	handle(c.dashboardAddr, "/events", c.handleDashboardEvents)
+	handle(c.apiAddr, "/api/state", c.handleAPIState)
+	handle(c.apiAddr, "/api/calls", c.handleAPICall)
+	handle(c.apiAddr, "/api/destinations", c.handleAPIDestination)
+	handle(c.apiAddr, "/api/orders/", c.handleAPICancelOrder)
+	handle(c.apiAddr, "/api/service", c.handleAPIService)
+	handle(c.apiAddr, "/api/fire", c.handleAPIFire)
+	handle(c.apiAddr, "/api/firefighter", c.handleAPIFirefighter)
+	handle(c.apiAddr, "/api/door", c.handleAPIDoor)
+	handle(c.apiAddr, "/api/independent", c.handleAPIIndependent)
+	handle(c.apiAddr, "/api/inspection", c.handleAPIInspection)
+	handle(c.apiAddr, "/api/traffic", c.handleAPITraffic)
+
+	for addr, mux := range muxes {
+		go c.listenAndServe(addr, mux)
+	}
+}
+
+func (c *Client) listenAndServe(addr string, handler http.Handler) {
+	server := &http.Server{Addr: addr, Handler: handler}
+	c.logger(logHTTP).Infof("Serving on %s", addr)
+
+	go func() {
+		<-c.ctx.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		server.Shutdown(ctx)
+	}()
+
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		c.logger(logHTTP).Errorf("The server on %s stopped: %v", addr, err)
+	}
+}
@@ -0,0 +1,95 @@
+// This file contains the independent service of the client: an operator takes a car out of the group dispatch
+// (e.g. to move furniture) and drives it with its cab calls. Its hall orders go back to the master, and the door
+// stays open at each stop until a cab call is pressed
+package elevator
+
+import (
+	"errors"
+	"time"
+)
+
+// IndependentServiceSwitch is the independent service key switch of a car, for the drivers that have one
+type IndependentServiceSwitch interface {
+	PollIndependentServiceSwitch(receiver chan<- bool)
+}
+
+// Puts the car in independent service, or back to normal
+func (c *Client) setIndependentService(on bool) error {
+	mode := c.currentMode()
+	switch {
+	case on == (mode == modeIndependent):
+		return nil
+	case on && mode != modeNormal:
+		return errors.New("The car is in " + mode + " mode")
+	case on && c.isShuttingDown():
+		return errors.New("The elevator is shutting down")
+	}
+
+	lockMutexes(&c.mutex_elevatorOrders, &c.mutex_d, &c.mutex_posArray)
+	if on {
+		c.setMode(modeIndependent)
+
+		// The hall orders are handed back to the master with the same state update (see masterRoutine)
+		cabOrders := []Order{}
+		for _, order := range c.elevatorOrders {
+			if order.OrderType == cab {
+				cabOrders = append(cabOrders, order)
+			}
+		}
+		c.elevatorOrders = cabOrders
+	} else {
+		c.setMode(modeNormal)
+	}
+	c.updateState(c.lastFloor)
+	c.singleStateTx <- StateMsg{c.id, c.latestState}
+	next := append([]Order{}, c.elevatorOrders...)
+	unlockMutexes(&c.mutex_elevatorOrders, &c.mutex_d, &c.mutex_posArray)
+
+	if on {
+		c.logEvent(logFSM, "Independent service: the car leaves the group dispatch")
+	} else {
+		c.logEvent(logFSM, "Independent service turned off, back to normal")
+	}
+
+	// The car goes on with its cab calls (once back to normal, the door closes after a while like at any stop)
+	if len(next) > 0 {
+		c.drv_newOrder <- next[0]
+	} else if !on && c.isDoorOpen() {
+		c.setDoorOpen(false)
+	}
+	return nil
+}
+
+func (c *Client) isDoorOpen() bool {
+	c.mutex_state.Lock()
+	defer c.mutex_state.Unlock()
+	return c.latestState.DoorOpen
+}
+
+// Called by attendToSpecificOrder (with its mutexes) once the car has stopped at a cab call in independent service:
+// the door opens and stays open, the car leaves when a cab call is pressed
+func (c *Client) stopForIndependentService(floor int) {
+	arrivedAt := time.Now()
+	served := c.popOrders()
+	c.updateState(floor)
+	c.singleStateTx <- StateMsg{c.id, c.latestState}
+	c.localStatesForCabOrders <- StateMsg{c.id, c.latestState}
+	c.setDoorOpen(true)
+	c.reportServedOrders(served, arrivedAt, time.Time{})
+	c.logger(logFSM).Debugf("Independent service: stopped at floor %d, waiting for a cab call", floor)
+}
+
+func (c *Client) handleIndependentServiceSwitch(serviceSwitch IndependentServiceSwitch) {
+	positions := make(chan bool)
+	go serviceSwitch.PollIndependentServiceSwitch(positions)
+	for {
+		select {
+		case on := <-positions:
+			if err := c.setIndependentService(on); err != nil {
+				c.logger(logDriver).Warnf("Independent service switch: %v", err)
+			}
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
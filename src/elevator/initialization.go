@@ -0,0 +1,28 @@
+package elevator
+
+import (
+	"Driver-go/elevio"
+)
+
+func (c *Client) initSingleElev(d elevio.MotorDirection) {
+	drv_finishedInitialization := make(chan bool)
+	c.turnOffAllLights()
+	go func() {
+		c.driver.SetMotorDirection(d)
+		for {
+			a := <-c.drv_floors
+			if a == 0 {
+				d = elevio.MD_Stop
+				c.driver.SetMotorDirection(d)
+				break
+			}
+		}
+		c.ableToCloseDoors = true
+
+		drv_finishedInitialization <- true
+	}()
+
+	<-drv_finishedInitialization
+
+	c.logger(logFSM).Infof("Initialization finished")
+}
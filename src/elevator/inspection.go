@@ -0,0 +1,199 @@
+// This file contains the inspection mode of the client: for maintenance, a car leaves the group dispatch, drops its
+// orders and is driven floor by floor (jog up, jog down, stop) from the API or elevctl. The client enforces the
+// safety rules: the car does not move while its door is open or obstructed, and not past the terminal floors
+package elevator
+
+import (
+	"Driver-go/elevio"
+	"errors"
+	"fmt"
+)
+
+const behaviourInspection = "inspection" // ElevState.Behavior of a car in inspection
+
+// FloorSensor reads the floor sensor of a car (-1 between floors). The drivers that implement it are checked before
+// each jog, the other ones are trusted to the tracked position. Either way a jog stops at the next floor it reaches
+type FloorSensor interface {
+	GetFloor() int
+}
+
+// Applies a command of the inspection mode: on, off, up, down or stop
+func (c *Client) inspect(command string) error {
+	if command != "off" { // The car is driven again, it does not end the inspection at the next floor
+		c.mutex_d.Lock()
+		c.leavingInspection = false
+		c.mutex_d.Unlock()
+	}
+
+	mode := c.currentMode()
+	switch {
+	case command == "on" && mode == modeInspection, command == "off" && mode != modeInspection:
+		return nil
+	case command == "on" && mode != modeNormal && mode != modeIndependent:
+		return fmt.Errorf("The car is in %s mode", mode)
+	case command == "on":
+		if mode == modeIndependent { // Its door may be held open
+			c.setDoorOpen(false)
+		}
+		c.startInspection()
+		return nil
+	case command != "off" && command != "up" && command != "down" && command != "stop":
+		return fmt.Errorf("Unknown command %q (on, off, up, down or stop)", command)
+	case mode != modeInspection:
+		return errors.New("The car is not in inspection")
+	case command == "off" && c.sensorFloor() == -1:
+		// Left between two floors: the jog goes on to the next floor sensor (down if the car was stopped), where
+		// the inspection ends (see jogFloorReached)
+		c.mutex_d.Lock()
+		c.leavingInspection = true
+		moving := c.d != elevio.MD_Stop
+		c.mutex_d.Unlock()
+		if !moving {
+			c.jog(elevio.MD_Down)
+		}
+		c.logEvent(logFSM, "Inspection turned off, the car goes to the next floor first")
+		return nil
+	case command == "off":
+		c.endInspection()
+		return nil
+	case command == "stop":
+		c.jog(elevio.MD_Stop)
+		return nil
+	}
+
+	// Safety rules
+	direction := elevio.MD_Up
+	if command == "down" {
+		direction = elevio.MD_Down
+	}
+	floor := c.sensorFloor()
+	switch {
+	case c.isDoorOpen():
+		return errors.New("The door is open")
+	case c.isObstructed():
+		return errors.New("The door is obstructed")
+	case direction == elevio.MD_Up && floor == numFloors-1:
+		return errors.New("The car is at the top floor")
+	case direction == elevio.MD_Down && floor == 0:
+		return errors.New("The car is at the bottom floor")
+	}
+	c.jog(direction)
+	return nil
+}
+
+// Stops the car, drops its orders (its hall orders go to the other elevators, see masterRoutine) and turns its
+// cab lights off
+func (c *Client) startInspection() {
+	c.setMode(modeInspection)
+	lockMutexes(&c.mutex_elevatorOrders, &c.mutex_d, &c.mutex_posArray)
+	cleared := len(c.elevatorOrders)
+	c.elevatorOrders = []Order{}
+	unlockMutexes(&c.mutex_elevatorOrders, &c.mutex_d, &c.mutex_posArray)
+	c.jog(elevio.MD_Stop)
+	c.sendInspectionState()
+	for f := 0; f < numFloors; f++ {
+		c.driver.SetButtonLamp(elevio.BT_Cab, f, false)
+	}
+	c.logEvent(logFSM, "Inspection: %d orders dropped, the car is driven manually", cleared)
+}
+
+// Sets the direction of the motor, the same way attendToSpecificOrder does
+func (c *Client) jog(direction elevio.MotorDirection) {
+	lockMutexes(&c.mutex_d, &c.mutex_posArray)
+	prev_direction := c.d
+	c.d = direction
+	c.driver.SetMotorDirection(c.d)
+	unlockMutexes(&c.mutex_d, &c.mutex_posArray)
+
+	// Communicate with trackPosition if our direction was altered
+	if prev_direction != direction {
+		c.drv_DirectionChange <- direction
+		c.sendInspectionState()
+	}
+}
+
+func (c *Client) sendInspectionState() {
+	lockMutexes(&c.mutex_elevatorOrders, &c.mutex_d, &c.mutex_posArray)
+	c.updateState(c.lastFloor)
+	c.singleStateTx <- StateMsg{c.id, c.latestState}
+	unlockMutexes(&c.mutex_elevatorOrders, &c.mutex_d, &c.mutex_posArray)
+}
+
+// Called by attendToSpecificOrder (with its mutexes) when the car reaches a floor in inspection: a jog moves the
+// car by one floor
+func (c *Client) jogFloorReached(floor int) {
+	c.d = elevio.MD_Stop
+	c.driver.SetMotorDirection(c.d)
+	leaving := c.leavingInspection
+	if leaving {
+		c.leavingInspection = false
+		c.setMode(modeNormal)
+	}
+	c.updateState(floor)
+	c.singleStateTx <- StateMsg{c.id, c.latestState}
+	c.logger(logFSM).Debugf("Inspection: stopped at floor %d", floor)
+	if leaving {
+		c.logEvent(logFSM, "Inspection turned off at floor %d, back to normal", floor)
+	}
+}
+
+// Stops the car at the floor where it is and gives it back to the group dispatch
+func (c *Client) endInspection() {
+	c.jog(elevio.MD_Stop)
+	c.setMode(modeNormal)
+	c.sendInspectionState()
+	c.logEvent(logFSM, "Inspection turned off, back to normal")
+}
+
+// Returns the floor of the car (-1 between floors), from its floor sensor if the driver has one
+func (c *Client) sensorFloor() int {
+	if sensor, ok := c.driver.(FloorSensor); ok {
+		return sensor.GetFloor()
+	}
+	lockMutexes(&c.mutex_posArray)
+	defer unlockMutexes(&c.mutex_posArray)
+	position := c.extractPos()
+	if position != float32(int(position)) {
+		return -1
+	}
+	return int(position)
+}
+
+func (c *Client) isObstructed() bool {
+	c.mutex_doors.Lock()
+	defer c.mutex_doors.Unlock()
+	return !c.ableToCloseDoors
+}
+
+// Applies the commands of elevctl inspect that are for us, and answers them
+func (c *Client) handleInspectionCommands() {
+	lastSeq, lastErr := "", ""
+	for {
+		var a InspectionMsg
+		select {
+		case a = <-c.inspectionRx:
+		case <-c.ctx.Done():
+			return
+		}
+		if a.Id != c.id {
+			continue
+		}
+
+		if a.Seq != lastSeq { // Else it was sent again, it is only answered again
+			lastSeq, lastErr = a.Seq, ""
+			if err := c.inspect(a.Command); err != nil {
+				lastErr = err.Error()
+				c.logger(logFSM).Warnf("Inspection command %s refused: %v", a.Command, err)
+			}
+		}
+
+		c.mutex_state.Lock()
+		ack := InspectionAckMsg{From: c.id, Seq: a.Seq, Error: lastErr, State: c.latestState}
+		c.mutex_state.Unlock()
+		select {
+		case c.inspectionTx <- ack:
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// This file contains the optional Prometheus endpoint of the client (see Config.MetricsAddr)
+package elevator
+
+import (
+	"Network-go/network/bcast"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// The network layers that count their messages per port (UDPTransport does)
+type countingTransport interface {
+	Counters() map[int]bcast.PortCounters
+}
+
+// The drivers that reconnect to their server (*elevio.Driver does)
+type reconnectingDriver interface {
+	Reconnects() int
+}
+
+// Counters returns the messages counted by bcast on each port (none for the zero UDPTransport)
+func (t UDPTransport) Counters() map[int]bcast.PortCounters {
+	return t.counters.Ports()
+}
+
+func (c *Client) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	c.writeMetrics(w)
+}
+
+// Writes a metric in the Prometheus text format. samples maps the labels ("" for none) to the values
+func writeMetric(w io.Writer, name, kind, help string, samples map[string]float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	labels := make([]string, 0, len(samples))
+	for label := range samples {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	for _, label := range labels {
+		if label == "" {
+			fmt.Fprintf(w, "%s %g\n", name, samples[label])
+		} else {
+			fmt.Fprintf(w, "%s{%s} %g\n", name, label, samples[label])
+		}
+	}
+}
+
+func (c *Client) writeMetrics(w io.Writer) {
+	// The length of the queue of every car: ours, and the others as last heard from the master
+	queues := map[string]float64{}
+	c.mutex_backup.Lock()
+	for id, state := range c.backupStates {
+		if state.Behavior != "Uninitialized" && id != c.id {
+			queues[fmt.Sprintf("elevator=\"%d\"", id)] = float64(len(state.LocalRequests))
+		}
+	}
+	c.mutex_backup.Unlock()
+	c.mutex_elevatorOrders.Lock()
+	queues[fmt.Sprintf("elevator=\"%d\"", c.id)] = float64(len(c.elevatorOrders))
+	c.mutex_elevatorOrders.Unlock()
+	writeMetric(w, "elevator_queue_length", "gauge", "The number of orders in the queue of each car.", queues)
+
+	c.mutex_metrics.Lock()
+	hallOrders := map[string]float64{
+		"status=\"pending\"":  float64(c.hallOrdersPending),
+		"status=\"assigned\"": float64(c.hallOrdersAssigned),
+	}
+	completed := float64(c.hallOrdersCompleted)
+	motorStops := float64(c.motorStopDetections)
+	obstructed := c.obstructedFor
+	if !c.obstructedSince.IsZero() {
+		obstructed += time.Since(c.obstructedSince)
+	}
+	c.mutex_metrics.Unlock()
+	writeMetric(w, "elevator_hall_orders", "gauge", "The hall orders tracked by the master, waiting for a confirmation or assigned (0 on the other elevators).", hallOrders)
+	writeMetric(w, "elevator_hall_orders_completed_total", "counter", "The hall orders served while this elevator was master.", map[string]float64{"": completed})
+	writeMetric(w, "elevator_hall_orders_reassigned_total", "counter", "The hall orders re-assigned by the watchdog while this elevator was master.", map[string]float64{"": float64(c.HallOrderReassignments())})
+
+	roles := map[string]float64{}
+	for _, role := range []string{"Master", "PrimaryBackup", "Regular"} {
+		roles[fmt.Sprintf("role=\"%s\"", role)] = 0
+	}
+	roles[fmt.Sprintf("role=\"%s\"", c.Role())] = 1
+	writeMetric(w, "elevator_role", "gauge", "The current role of the elevator.", roles)
+
+	c.mutex_peers.Lock()
+	peerCount := float64(len(c.peers))
+	c.mutex_peers.Unlock()
+	writeMetric(w, "elevator_peers", "gauge", "The number of elevators on the network, including this one.", map[string]float64{"": peerCount})
+
+	if transport, ok := c.transport.(countingTransport); ok {
+		messages := map[string]float64{}
+		decodeFailures := map[string]float64{}
+		for port, counters := range transport.Counters() {
+			messages[fmt.Sprintf("port=\"%d\",direction=\"sent\"", port)] = float64(counters.Sent)
+			messages[fmt.Sprintf("port=\"%d\",direction=\"received\"", port)] = float64(counters.Received)
+			messages[fmt.Sprintf("port=\"%d\",direction=\"dropped\"", port)] = float64(counters.Dropped)
+			decodeFailures[fmt.Sprintf("port=\"%d\"", port)] = float64(counters.DecodeFailures)
+		}
+		writeMetric(w, "elevator_bcast_messages_total", "counter", "The messages sent, received and dropped on each port.", messages)
+		writeMetric(w, "elevator_bcast_decode_failures_total", "counter", "The messages received on each port that could not be decoded.", decodeFailures)
+	}
+
+	writeMetric(w, "elevator_motor_stop_detections_total", "counter", "The elevators detected as stopped (power loss) while this elevator was master.", map[string]float64{"": motorStops})
+	writeMetric(w, "elevator_obstruction_seconds_total", "counter", "The time the door was obstructed.", map[string]float64{"": obstructed.Seconds()})
+
+	if driver, ok := c.driver.(reconnectingDriver); ok {
+		writeMetric(w, "elevator_driver_reconnects_total", "counter", "The times the connection to the elevator server was opened again.", map[string]float64{"": float64(driver.Reconnects())})
+	}
+}
+
+// Publishes the number of hall orders tracked by the master, for the metrics
+func (c *Client) setHallOrderMetrics(hallOrders map[orderKey]hallOrderRecord) {
+	pending, assignedOrders := 0, 0
+	for _, record := range hallOrders {
+		if record.Status == assigned {
+			assignedOrders++
+		} else {
+			pending++
+		}
+	}
+	c.mutex_metrics.Lock()
+	c.hallOrdersPending, c.hallOrdersAssigned = pending, assignedOrders
+	c.mutex_metrics.Unlock()
+}
+
+// Counts hall orders served, for the metrics
+func (c *Client) countCompletedHallOrders(n int) {
+	c.mutex_metrics.Lock()
+	c.hallOrdersCompleted += n
+	c.mutex_metrics.Unlock()
+}
@@ -0,0 +1,194 @@
+// This file contains the parking of the idle cars. When a car has been idle for Config.ParkingDelay (in the group
+// dispatch, without orders and with its door closed), the master sends it to the floor given by the parking
+// policy: the lobby, the middle of its zone, or one of the floors where the most hall calls come from. During an
+// up-peak or a down-peak the traffic mode parks the cars instead, right away (see traffic.go).
+// A parked car goes to its floor as if it was an order but does not open its door there, and any order it gets
+// cancels the parking
+package elevator
+
+import (
+	"Driver-go/elevio"
+	"math"
+	"sort"
+	"time"
+)
+
+const parkOrderId = "park" // The id of the order that sends an idle car to the floor where it waits (see parkAt)
+
+// Tells whether policy is one of our parking policies. The empty one is the zero value of Config.ParkingPolicy,
+// taken as none
+func validParkingPolicy(policy string) bool {
+	return policy == "" || policy == parkingNone || policy == parkingLobby || policy == parkingZones || policy == parkingDemand
+}
+
+// Returns the idle cars that have been idle for at least delay. idleSince keeps when each car became idle (zero
+// for the cars that are not)
+func idleFor(idle []int, idleSince *[numElev]time.Time, delay time.Duration) []int {
+	now := time.Now()
+	isIdle := make(map[int]bool)
+	ready := []int{}
+	for _, id := range idle {
+		isIdle[id] = true
+		if idleSince[id].IsZero() {
+			idleSince[id] = now
+		}
+		if now.Sub(idleSince[id]) >= delay {
+			ready = append(ready, id)
+		}
+	}
+	for id := range idleSince {
+		if !isIdle[id] {
+			idleSince[id] = time.Time{}
+		}
+	}
+	return ready
+}
+
+// Returns the floors where the cars idle for the parking delay (ready) wait in normal traffic, following our parking
+// policy (id -> floor), except the cars that are already there. rates are the hall calls per minute at each floor
+// (see trafficRates)
+func (c *Client) policyParkingFloors(allStates [numElev]ElevState, idle, ready []int, rates [][2]float64) map[int]int {
+	floors := make(map[int]int)
+	switch c.parkingPolicy {
+	case parkingLobby:
+		for _, id := range ready {
+			floors[id] = c.lobbyFloor
+		}
+
+	case parkingZones:
+		// One zone per car of the group dispatch, the busy ones are already in theirs
+		dispatch := []int{}
+		for _, id := range c.candidateElevators(allStates, -1) {
+			if dispatchable(allStates[id].Mode) {
+				dispatch = append(dispatch, id)
+			}
+		}
+		zones := spreadOver(allStates, dispatch, 0, numFloors-1)
+		for _, id := range ready {
+			if floor, ok := zones[id]; ok {
+				floors[id] = floor
+			}
+		}
+
+	case parkingDemand:
+		floors = demandFloors(allStates, idle, ready, rates)
+	}
+	return notThereYet(allStates, floors)
+}
+
+// Spreads cars evenly over the floors from lowest to highest (id -> floor): each one gets the middle of an equal
+// share of them, the lowest car the lowest share
+func spreadOver(allStates [numElev]ElevState, ids []int, lowest, highest int) map[int]int {
+	floors := make(map[int]int)
+	sorted := append([]int{}, ids...)
+	sort.SliceStable(sorted, func(i, j int) bool { return allStates[sorted[i]].Floor < allStates[sorted[j]].Floor })
+	count := highest - lowest + 1
+	for i, id := range sorted {
+		floors[id] = lowest + (2*i+1)*count/(2*len(sorted))
+	}
+	return floors
+}
+
+// Sends the ready cars to the floors with the most hall calls, the busiest floor first, each one to the nearest
+// car left. A floor where an idle car already waits is not given another one, nor are the floors without calls
+func demandFloors(allStates [numElev]ElevState, idle, ready []int, rates [][2]float64) map[int]int {
+	busiest := []int{}
+	for floor, rate := range rates {
+		if rate[0]+rate[1] > 0 {
+			busiest = append(busiest, floor)
+		}
+	}
+	sort.SliceStable(busiest, func(i, j int) bool {
+		return rates[busiest[i]][0]+rates[busiest[i]][1] > rates[busiest[j]][0]+rates[busiest[j]][1]
+	})
+
+	taken := make(map[int]bool) // The cars that wait at one of the floors, or are sent to one
+	uncovered := []int{}
+	for _, floor := range busiest {
+		covered := false
+		for _, id := range idle {
+			if !taken[id] && allStates[id].Floor == floor {
+				taken[id], covered = true, true
+				break
+			}
+		}
+		if !covered {
+			uncovered = append(uncovered, floor)
+		}
+	}
+
+	floors := make(map[int]int)
+	for _, floor := range uncovered {
+		nearest := -1
+		for _, id := range ready {
+			distance := math.Abs(float64(allStates[id].Floor - floor))
+			if !taken[id] && (nearest == -1 || distance < math.Abs(float64(allStates[nearest].Floor-floor))) {
+				nearest = id
+			}
+		}
+		if nearest == -1 {
+			break
+		}
+		taken[nearest] = true
+		floors[nearest] = floor
+	}
+	return floors
+}
+
+// Leaves out the cars that already wait at their floor. A car that does not serve its floor waits at the nearest
+// one it serves (see zoning.go)
+func notThereYet(allStates [numElev]ElevState, floors map[int]int) map[int]int {
+	for id, floor := range floors {
+		floor = nearestServedFloor(allStates[id], floor)
+		floors[id] = floor
+		if allStates[id].Floor == floor {
+			delete(floors, id)
+		}
+	}
+	return floors
+}
+
+// Sends the car to a floor where it waits for its next order with its door closed (see attendToSpecificOrder).
+// Only an idle car is moved, and any order it gets replaces the parking
+func (c *Client) parkAt(floor int) {
+	if !c.takesOrders() || c.isShuttingDown() || c.isDoorOpen() {
+		return
+	}
+	lockMutexes(&c.mutex_elevatorOrders, &c.mutex_d)
+	busy := len(c.elevatorOrders) > 0 || c.d != elevio.MD_Stop
+	unlockMutexes(&c.mutex_elevatorOrders, &c.mutex_d)
+	if busy {
+		return
+	}
+
+	c.logger(logFSM).Debugf("Parking at floor %d", floor)
+	c.drv_newOrder <- Order{Floor: floor, OrderType: cab, Id: parkOrderId}
+}
+
+// Called by attendToSpecificOrder (with its mutexes) once the car has stopped where it was parked: no door cycle
+func (c *Client) parked(floor int) {
+	c.updateState(floor)
+	c.singleStateTx <- StateMsg{c.id, c.latestState}
+	c.logger(logFSM).Debugf("Parked at floor %d", floor)
+}
+
+func (c *Client) hasOrders() bool {
+	c.mutex_elevatorOrders.Lock()
+	defer c.mutex_elevatorOrders.Unlock()
+	return len(c.elevatorOrders) > 0
+}
+
+// Moves the car to the floors chosen by the master
+func (c *Client) handleParkRequests() {
+	for {
+		var a ParkMsg
+		select {
+		case a = <-c.parkRx:
+		case <-c.ctx.Done():
+			return
+		}
+		if a.Id == c.id {
+			c.parkAt(a.Floor)
+		}
+	}
+}
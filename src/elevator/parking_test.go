@@ -0,0 +1,107 @@
+package elevator
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Returns the states of the elevators standing at floors
+func statesAt(floors ...int) [numElev]ElevState {
+	var allStates [numElev]ElevState
+	for id, floor := range floors {
+		allStates[id].Floor = floor
+	}
+	return allStates
+}
+
+func TestValidParkingPolicy(t *testing.T) {
+	for policy, want := range map[string]bool{"": true, "none": true, "lobby": true, "zones": true, "demand": true, "nearest": false, "Lobby": false} {
+		if got := validParkingPolicy(policy); got != want {
+			t.Errorf("validParkingPolicy(%q) = %v, want %v", policy, got, want)
+		}
+	}
+}
+
+func TestSpreadOver(t *testing.T) {
+	tests := []struct {
+		name            string
+		allStates       [numElev]ElevState
+		ids             []int
+		lowest, highest int
+		want            map[int]int
+	}{
+		{"no car", statesAt(0, 0, 0), nil, 0, numFloors - 1, map[int]int{}},
+		{"one car in the middle", statesAt(0, 0, 0), []int{1}, 0, numFloors - 1, map[int]int{1: 2}},
+		{"the lowest car gets the lowest zone", statesAt(3, 0, 0), []int{0, 1}, 0, numFloors - 1, map[int]int{1: 1, 0: 3}},
+		{"three cars", statesAt(2, 1, 0), []int{0, 1, 2}, 0, numFloors - 1, map[int]int{2: 0, 1: 2, 0: 3}},
+		{"the upper floors of a down-peak", statesAt(0, 0, 0), []int{0, 2}, 1, numFloors - 1, map[int]int{0: 1, 2: 3}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := spreadOver(test.allStates, test.ids, test.lowest, test.highest); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("spreadOver() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestDemandFloors(t *testing.T) {
+	// Returns the hall calls per minute at each floor, all of them going up
+	ratesAt := func(perFloor map[int]float64) [][2]float64 {
+		rates := make([][2]float64, numFloors)
+		for floor, rate := range perFloor {
+			rates[floor][0] = rate
+		}
+		return rates
+	}
+	tests := []struct {
+		name        string
+		allStates   [numElev]ElevState
+		idle, ready []int
+		rates       [][2]float64
+		want        map[int]int
+	}{
+		{
+			name:      "no calls",
+			allStates: statesAt(0, 1, 2),
+			idle:      []int{0, 1, 2}, ready: []int{0, 1, 2},
+			rates: ratesAt(nil),
+			want:  map[int]int{},
+		},
+		{
+			name:      "an idle car already waits at the busiest floor",
+			allStates: statesAt(0, 3, 1),
+			idle:      []int{0, 1, 2}, ready: []int{0, 1, 2},
+			rates: ratesAt(map[int]float64{3: 4}),
+			want:  map[int]int{},
+		},
+		{
+			name:      "the busiest floor first, each one to the nearest car left",
+			allStates: statesAt(1, 3, 3),
+			idle:      []int{0, 1, 2}, ready: []int{0, 1, 2},
+			rates: ratesAt(map[int]float64{2: 6, 0: 3}),
+			want:  map[int]int{0: 2, 1: 0},
+		},
+		{
+			name:      "fewer ready cars than busy floors",
+			allStates: statesAt(1, 3, 3),
+			idle:      []int{0, 1, 2}, ready: []int{2},
+			rates: ratesAt(map[int]float64{2: 6, 0: 3}),
+			want:  map[int]int{2: 2},
+		},
+		{
+			name:      "a car idle for less than the delay covers its floor but is not sent",
+			allStates: statesAt(2, 0, 0),
+			idle:      []int{0, 1}, ready: []int{1},
+			rates: ratesAt(map[int]float64{2: 5, 3: 2}),
+			want:  map[int]int{1: 3},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := demandFloors(test.allStates, test.idle, test.ready, test.rates); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("demandFloors() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,773 @@
+package elevator
+
+import (
+	"Driver-go/elevio"
+	"Network-go/network/peers"
+	"context"
+	"sort"
+	"time"
+)
+
+func (c *Client) handleFloorLights(consumer3drv_floors chan int) {
+	for {
+		select {
+		case a := <-consumer3drv_floors:
+			c.driver.SetFloorIndicator(a)
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// obstructionToReAssignment
+
+func (c *Client) handleObstruction() {
+	for {
+		select {
+		case a := <-c.drv_obstr:
+			if a { // If it is on
+				lockMutexes(&c.mutex_doors)
+				c.ableToCloseDoors = false
+				unlockMutexes(&c.mutex_doors)
+				c.mutex_metrics.Lock()
+				if c.obstructedSince.IsZero() {
+					c.obstructedSince = time.Now()
+				}
+				c.mutex_metrics.Unlock()
+				c.logEvent(logDriver, "Obstruction on")
+				if c.currentMode() == modeInspection { // No movement while the door is obstructed
+					c.jog(elevio.MD_Stop)
+				}
+			} else { // If it is off
+				lockMutexes(&c.mutex_doors)
+				c.ableToCloseDoors = true
+				unlockMutexes(&c.mutex_doors)
+				c.mutex_metrics.Lock()
+				if !c.obstructedSince.IsZero() {
+					c.obstructedFor += time.Since(c.obstructedSince)
+					c.obstructedSince = time.Time{}
+				}
+				c.mutex_metrics.Unlock()
+				c.logEvent(logDriver, "Obstruction off")
+			}
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+/* func reAssignHallOrdersObstruction() {
+	timestamp := time.Now()
+
+
+
+	//redistributeOrders(localRequest []Order, hallBtnTx chan Order)
+
+} */
+
+func (c *Client) handleElevatorUpdate() {
+	for {
+		select {
+		case a := <-c.activeElevatorsChannelRx:
+			lockMutexes(&c.mutex_activeElevators)
+			c.activeElevators = a
+			unlockMutexes(&c.mutex_activeElevators)
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Client) handleButtonPress() {
+	for {
+		var a elevio.ButtonEvent
+		select {
+		case a = <-c.drv_buttons_forOrderHandling: // BUTTON UPDATE
+		case <-c.ctx.Done():
+			return
+		}
+
+		c.logger(logDriver).Debugf("Button %d pressed at floor %d", a.Button, a.Floor)
+		if a.Button == elevio.BT_Cab && !c.takesCabCalls() || a.Button != elevio.BT_Cab && c.inFireService() { // e.g. during the fire recall
+			continue
+		}
+
+		// If it's a hall order, forwards it to the master
+		switch {
+		case a.Button == elevio.BT_Destination && !c.destinationDispatch:
+			c.logger(logDriver).Warnf("Destination %d entered at floor %d ignored, destination dispatch is off", a.Destination, a.Floor)
+		case a.Button == elevio.BT_Destination: // The master tells which car takes it (see destinationDispatch.go)
+			c.enterDestination(c.destinationCall(a.Floor, a.Destination))
+		case (a.Button == elevio.BT_HallUp || a.Button == elevio.BT_HallDown) && c.destinationDispatch:
+			// The passengers enter their destination instead
+		case a.Button == elevio.BT_Cab && !c.servesFloor(a.Floor): // We do not stop there (see zoning.go)
+			c.logger(logDriver).Infof("Cab call to floor %d refused, the car does not serve it", a.Floor)
+			go c.blinkLamp(a.Button, a.Floor)
+
+		case (a.Button == elevio.BT_HallUp || a.Button == elevio.BT_HallDown) && c.isIsolated() && (!c.takesOrders() || !c.servesFloor(a.Floor)):
+			// We are offline and out of the group dispatch, or we do not stop there: nobody can take the order
+		case (a.Button == elevio.BT_HallUp || a.Button == elevio.BT_HallDown) && c.isIsolated() && !c.isShuttingDown():
+			// We are offline: nobody else can take the order, so we serve it ourselves.
+			// It is handed to the master with our other orders when we rejoin the cluster
+			order := c.newOrder(btnPressToOrder(a)).assignedTo(c.id)
+			c.turnOnHallLights(order)
+			c.takeOrder(order)
+
+		case a.Button == elevio.BT_HallUp || a.Button == elevio.BT_HallDown: // If it's a hall order
+
+			c.hallBtnTx <- c.newOrder(btnPressToOrder(a)) // Send the hall order to the master
+
+		case a.Button == elevio.BT_Cab && c.isShuttingDown(): // We are leaving, the cab orders are not taken anymore
+
+		case a.Button == elevio.BT_Cab: // Else (it's a cab)
+			c.takeOrder(c.newOrder(Order{Floor: a.Floor, Direction: 0, OrderType: cab}).assignedTo(c.id))
+		}
+	}
+}
+
+// Adds an order to the local elevatorOrders and sends the first one to the driver
+func (c *Client) takeOrder(order Order) {
+	lockMutexes(&c.mutex_elevatorOrders, &c.mutex_d, &c.mutex_posArray)
+	c.addOrder(order)                                 // Add the order to the local elevatorOrders
+	sortAllOrders(&c.elevatorOrders, c.d, c.posArray) // Sort the orders
+	first_element := c.elevatorOrders[0]
+
+	// Update & send the new state of the elevator to the master
+	c.updateState(c.lastFloor)
+	c.singleStateTx <- StateMsg{c.id, c.latestState}
+	unlockMutexes(&c.mutex_elevatorOrders, &c.mutex_d, &c.mutex_posArray)
+
+	c.drv_newOrder <- first_element // Send the first element of the elevatorOrders to the driver
+}
+
+func (c *Client) handleNewFloorReached(consumer1drv_floors chan int) {
+	for {
+		select {
+		case a := <-consumer1drv_floors:
+			c.lastFloor = a // Update the last floor
+
+			// Update & send the new state of the elevator to the master
+
+			c.updateState(c.lastFloor)
+			c.singleStateTx <- StateMsg{c.id, c.latestState}
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Client) handleStopButton() {
+	id := c.id
+	for {
+		var a bool
+		select {
+		case a = <-c.drv_stop: // STOP BUTTON
+		case <-c.ctx.Done():
+			return
+		}
+
+		switch {
+		case a:
+			// Rising edge, from unpressed to pressed
+			c.logEvent(logDriver, "Stop button pressed")
+			lockMutexes(&c.mutex_d)
+
+			// Stop the elevator
+			c.driver.SetStopLamp(true)
+			c.lastDirForStopFunction = c.d // Save the last direction before stopping, ## PLACEHOLDER ##
+			c.driver.SetMotorDirection(elevio.MD_Stop)
+
+			unlockMutexes(&c.mutex_d)
+
+			// The elevator removes himself from the activeElevators list and sends it to the other elevators
+			c.mutex_activeElevators.Lock()
+			alreadyExists := c.isElevatorActive(id)
+			c.mutex_activeElevators.Unlock()
+			if alreadyExists {
+				c.mutex_activeElevators.Lock()
+				c.removeElevator(id)
+				c.mutex_activeElevators.Unlock()
+
+				c.activeElevatorsChannelTx <- c.activeElevators
+			}
+
+			// Re-assign the hall orders, i.e. send them again to the master
+			for _, order := range c.elevatorOrders {
+				if order.OrderType == hall {
+					c.hallBtnTx <- order
+				}
+			}
+
+		case !a:
+			// Falling edge, from pressed to unpressed
+			c.logEvent(logDriver, "Stop button released")
+			lockMutexes(&c.mutex_d)
+			c.driver.SetMotorDirection(c.lastDirForStopFunction) // Start the elevator again in the last direction ## PLACEHOLDER ##
+			unlockMutexes(&c.mutex_d)
+
+			c.driver.SetStopLamp(false)
+
+			// The elevator adds himself to the activeElevators list and sends it to the other elevators
+			c.mutex_activeElevators.Lock()
+			alreadyExists := c.isElevatorActive(id)
+			c.mutex_activeElevators.Unlock()
+			if !alreadyExists {
+				c.mutex_activeElevators.Lock()
+				c.activeElevators = append(c.activeElevators, id)
+				c.activeElevators = sortElevators(c.activeElevators)
+				c.mutex_activeElevators.Unlock()
+
+				c.activeElevatorsChannelTx <- c.activeElevators
+			}
+		}
+	}
+}
+
+func (c *Client) handleNewHallOrder() {
+	for {
+		var a HallOrderMsg
+		select {
+		case a = <-c.hallOrderRx: // NEW ORDER FROM THE MASTER
+		case <-c.ctx.Done():
+			return
+		}
+
+		// The lights are turned on once the order is confirmed (see handleHallLights)
+
+		// An order at the floor where we are waiting is added like the other ones: it reopens the door and it is
+		// removed from our state, so that the master sees it served
+
+		// Checking if we are the elevator that should take the order
+		if a.Id == c.id && c.inFireService() {
+			// The master assigned it before the fire recall, it was cancelled
+		} else if a.Id == c.id && (c.isShuttingDown() || !c.takesOrders()) {
+			// We are leaving (or out of the group dispatch), send the order back to the master
+			c.redistributeOrders([]Order{a.HallOrder})
+		} else if a.Id == c.id {
+
+			newHallOrder := a.HallOrder
+
+			lockMutexes(&c.mutex_elevatorOrders, &c.mutex_d, &c.mutex_posArray)
+
+			c.addOrder(newHallOrder)                          // Add the hall order to the local elevatorOrders
+			sortAllOrders(&c.elevatorOrders, c.d, c.posArray) // Sort the orders
+			first_element := c.elevatorOrders[0]
+
+			// Update & send the new state of the elevator to the master
+			c.updateState(c.lastFloor)
+			c.singleStateTx <- StateMsg{c.id, c.latestState}
+
+			unlockMutexes(&c.mutex_elevatorOrders, &c.mutex_d, &c.mutex_posArray)
+
+			c.drv_newOrder <- first_element // Send the first element of the elevatorOrders to the driver
+		}
+	}
+}
+
+func (c *Client) handlePeerUpdate() {
+	leftPeers := make(map[int]bool) // The elevators that announced that they were shutting down
+
+	for {
+		var p peers.PeerUpdate
+		select {
+		case p = <-c.peerUpdateCh: // PEER UPDATE
+		case l := <-c.leaveRx: // An elevator announces that it is shutting down
+			if l.Id == c.id {
+				continue
+			}
+			if !validElevatorId(l.Id) {
+				c.logger(logPeers).Warnf("Shutdown of elevator %d ignored, there is no such elevator", l.Id)
+				continue
+			}
+			c.leaveAckTx <- LeaveAckMsg{Id: l.Id, From: c.id}
+
+			if leftPeers[l.Id] { // The message is sent until it is acknowledged, we only handle it once
+				continue
+			}
+			leftPeers[l.Id] = true
+
+			c.logEvent(logPeers, "Elevator %d (%s) is shutting down", l.Id, l.Role)
+
+			// Use the final snapshot of the leaving elevator
+			c.mutex_backup.Lock()
+			if l.Role == "Master" {
+				c.backupStates = l.States
+			}
+			c.backupStates[l.Id] = l.States[l.Id]
+			c.mutex_backup.Unlock()
+
+			// The survivors are the peers we know of, except the leaving elevator
+			survivors := []peers.ElevIdentity{}
+			c.mutex_peers.Lock()
+			for _, peer := range c.peers {
+				if peer.Id != l.Id {
+					survivors = append(survivors, peer)
+				}
+			}
+			c.mutex_peers.Unlock()
+
+			c.handleLostElevators([]peers.ElevIdentity{{Id: l.Id, Role: l.Role}}, l.HallOrders, survivors, false)
+			continue
+		case a := <-c.leaveAckRx:
+			if a.Id == c.id {
+				select {
+				case c.leaveAcks <- a.From:
+				default:
+				}
+			}
+			continue
+		case <-c.ctx.Done():
+			return
+		}
+
+		var mPeers = p.Peers
+		var mNew = p.New
+		var mLost = p.Lost
+
+		c.mutex_peers.Lock()
+		c.peers = mPeers
+		c.mutex_peers.Unlock()
+
+		// Display the peer update
+		c.logger(logPeers).Debugf("Peer update: peers %v, new %v, lost %v", mPeers, mNew, mLost)
+		if mNew != (peers.ElevIdentity{}) {
+			c.logEvent(logPeers, "Elevator %d (%s) is on the network", mNew.Id, mNew.Role)
+		}
+		for _, lost := range mLost {
+			c.logEvent(logPeers, "Elevator %d (%s) is lost", lost.Id, lost.Role)
+		}
+
+		switch { // Lost or New Peer?
+		case mNew != (peers.ElevIdentity{}): // A new peer joins the network
+
+			delete(leftPeers, mNew.Id)
+
+			// When an elevator looses network, it thinks that the other ones are down (and may become master).
+			// When it sees them again, it rejoins the cluster: the master merges its orders and gives it its role
+			if mNew.Id != c.id && c.isIsolated() {
+				go c.rejoin()
+			}
+
+			// The master updates the activeElevators array and sends it to the other elevators
+			if c.Role() == "Master" {
+				c.mutex_activeElevators.Lock()
+				alreadyExists := c.isElevatorActive(mNew.Id) // Check if the elevator is already active
+
+				if !alreadyExists {
+					c.activeElevators = append(c.activeElevators, mNew.Id) // Add the elevator to the activeElevators list
+				}
+
+				c.activeElevators = sortElevators(c.activeElevators) // Sort for the mapping to remain correct (see communication.go)
+				c.mutex_activeElevators.Unlock()
+
+				c.activeElevatorsChannelTx <- c.activeElevators // Send the activeElevators list to the other elevator
+			}
+
+		case len(mLost) > 0: // One or several peers leave the network
+
+			// Gather the lost elevators and all their orders
+			lostElevators := []peers.ElevIdentity{}
+			lostOrders := []Order{}
+			for _, lostElevator := range mLost {
+				if leftPeers[lostElevator.Id] { // Its shutdown was already handled
+					continue
+				}
+				lostElevators = append(lostElevators, lostElevator)
+
+				c.mutex_backup.Lock()
+				lostOrders = append(lostOrders, c.backupStates[lostElevator.Id].LocalRequests...)
+				c.mutex_backup.Unlock()
+			}
+
+			if len(lostElevators) == 0 {
+				continue
+			}
+
+			// Our own heartbeat is not received anymore when we are disconnected from the network
+			disconnected := !isPeer(mPeers, c.id)
+
+			c.handleLostElevators(lostElevators, lostOrders, mPeers, disconnected)
+		}
+
+		// Display role changes
+	}
+}
+
+// Takes over the roles of the elevators that left the network and re-assigns all their hall orders.
+// survivors are the elevators still on the network, disconnected is true when it is us that lost the network
+func (c *Client) handleLostElevators(lostElevators []peers.ElevIdentity, lostOrders []Order, survivors []peers.ElevIdentity, disconnected bool) {
+	currentRole := c.Role()
+
+	// Section_START -- CHANGING ROLES
+	// Every elevator computes the roles from the same set of survivors, so that they agree on them
+	newRole := assignRoles(survivors, c.id, currentRole)[c.id]
+
+	if disconnected { // This means that we were disconnected from the network
+		newRole = "Regular"
+	}
+
+	// Without any other elevator, we have to rejoin the cluster when the network comes back
+	if disconnected || len(survivors) == 0 || (len(survivors) == 1 && survivors[0].Id == c.id) {
+		c.setIsolated(true)
+	}
+
+	// Remove the lost elevators from the activeElevators list before a new master starts assigning orders
+	c.mutex_activeElevators.Lock()
+	for _, lostElevator := range lostElevators {
+		if c.isElevatorActive(lostElevator.Id) {
+			c.removeElevator(lostElevator.Id)
+		}
+	}
+	c.activeElevators = sortElevators(c.activeElevators)
+	c.mutex_activeElevators.Unlock()
+
+	promoted := newRole == "Master" && currentRole != "Master"
+	if newRole != currentRole {
+		// Stop the routines of our previous role and launch the new ones.
+		// A new master re-assigns the lost orders itself, as nobody is listening for them yet
+		c.startRoleRoutines(newRole, removeDuplicateOrders(lostOrders))
+
+		c.logEvent(logPeers, "New role: %s (was %s)", newRole, currentRole)
+		currentRole = newRole
+		c.setRole(currentRole)
+		c.roleChannel <- currentRole
+	} else {
+		c.logger(logPeers).Debugf("The role stays %s", currentRole)
+	}
+
+	// The master sends the updated activeElevator list to the other elevators
+	if currentRole == "Master" {
+		c.activeElevatorsChannelTx <- c.activeElevators
+	}
+
+	// Section_END -- CHANGING ROLES
+
+	// Section_START -- RE-ASSIGNING ORDERS
+	// Re-assign the orders of the lost elevators. This is the job of the master
+	// (a newly promoted master already did it when starting its routine)
+	if currentRole == "Master" && !promoted {
+		// We can just send the hall orders to the master
+		// because it only takes into account the elevators that are inside of the activeElevators list
+		// and the lost elevators are not in it
+		c.redistributeOrders(removeDuplicateOrders(lostOrders))
+	}
+	// Section_END -- RE-ASSIGNING ORDERS
+}
+
+func hasOtherMaster(peerList []peers.ElevIdentity, id int) bool {
+	for _, peer := range peerList {
+		if peer.Id != id && peer.Role == "Master" {
+			return true
+		}
+	}
+	return false
+}
+
+// Called when we see other elevators again after being isolated from them.
+// Announces our orders and our last known states to the master until it acknowledges them, then takes the role
+// it gave us. This is repeated because the master might not be listening yet (e.g. it was just elected).
+// If no other master shows up, the elevators that came back together elect one (which may be us)
+func (c *Client) rejoin() {
+	c.mutex_rejoin.Lock()
+	if c.rejoinCancel != nil { // Already rejoining
+		c.mutex_rejoin.Unlock()
+		return
+	}
+	ctx, cancel := context.WithCancel(c.ctx)
+	c.rejoinCancel = cancel
+	c.mutex_rejoin.Unlock()
+	defer c.stopRejoin()
+
+	rejoin := RejoinMsg{Id: c.id, Role: c.Role()}
+	c.mutex_elevatorOrders.Lock()
+	rejoin.Orders = append([]Order{}, c.elevatorOrders...)
+	c.mutex_elevatorOrders.Unlock()
+	c.mutex_isolated.Lock()
+	rejoin.Served = append([]Order{}, c.servedOffline...)
+	c.mutex_isolated.Unlock()
+	c.mutex_backup.Lock()
+	rejoin.States = c.backupStates
+	c.mutex_backup.Unlock()
+	c.mutex_state.Lock()
+	rejoin.States[c.id] = c.latestState
+	c.mutex_state.Unlock()
+
+	c.logEvent(logPeers, "Rejoining the cluster")
+
+	var ack RejoinAckMsg
+	noMasterSince := time.Now()
+waitForAck:
+	for {
+		c.mutex_peers.Lock()
+		mPeers := c.peers
+		c.mutex_peers.Unlock()
+
+		if hasOtherMaster(mPeers, c.id) {
+			noMasterSince = time.Now()
+		} else if time.Since(noMasterSince) > rejoinElectionDelay {
+			// Every elevator was isolated and there is no other master: elect one the same way as for a lost peer
+			c.handleLostElevators(nil, nil, mPeers, false)
+			if c.Role() == "Master" {
+				c.setIsolated(false) // The other elevators are joining us
+				return
+			}
+			noMasterSince = time.Now()
+		}
+
+		select {
+		case c.rejoinTx <- rejoin:
+		case <-ctx.Done():
+			return
+		}
+
+		timeout := time.After(resendRateRejoin)
+		for {
+			select {
+			case ack = <-c.rejoinAckRx:
+				if ack.Id == c.id {
+					break waitForAck
+				}
+			case <-timeout:
+				continue waitForAck
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+
+	c.setIsolated(false)
+
+	// Take the role given by the master (if we became master while isolated, we step down)
+	if ack.Role != c.Role() {
+		c.startRoleRoutines(ack.Role, nil)
+		c.setRole(ack.Role)
+		c.roleChannel <- ack.Role
+	}
+
+	c.logEvent(logPeers, "Rejoined the cluster of master %d as %s", ack.From, ack.Role)
+}
+
+// Computes the role of every surviving elevator: the Master keeps its role if it survived, otherwise the
+// PrimaryBackup is promoted (or the elevator with the lowest id if both were lost).
+// The PrimaryBackup is then chosen the same way among the remaining elevators, and all the others are Regular
+func assignRoles(survivors []peers.ElevIdentity, id int, currentRole string) map[int]string {
+	// Sort the survivors by id (and use our own current role, which might not have been broadcasted yet)
+	sorted := []peers.ElevIdentity{{Id: id, Role: currentRole}}
+	for _, survivor := range survivors {
+		if survivor.Id != id {
+			sorted = append(sorted, survivor)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Id < sorted[j].Id
+	})
+
+	roles := make(map[int]string)
+
+	// Returns the first elevator without a new role, preferably with one of the given current roles
+	pick := func(preferredRoles ...string) int {
+		for _, preferredRole := range preferredRoles {
+			for _, elevator := range sorted {
+				if _, assigned := roles[elevator.Id]; !assigned && elevator.Role == preferredRole {
+					return elevator.Id
+				}
+			}
+		}
+		for _, elevator := range sorted {
+			if _, assigned := roles[elevator.Id]; !assigned {
+				return elevator.Id
+			}
+		}
+		return -1
+	}
+
+	if master := pick("Master", "PrimaryBackup"); master != -1 {
+		roles[master] = "Master"
+	}
+	if backup := pick("PrimaryBackup"); backup != -1 {
+		roles[backup] = "PrimaryBackup"
+	}
+	for _, elevator := range sorted {
+		if _, assigned := roles[elevator.Id]; !assigned {
+			roles[elevator.Id] = "Regular"
+		}
+	}
+
+	return roles
+}
+
+func (c *Client) handleTurnOffLightsHallOrderCompleted() {
+	for {
+		select {
+		case a := <-c.hallOrderCompletedLightsRx: // HALL ORDER COMPLETED
+			c.turnOffHallLights(a...)
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// Reconciles the hall lights with the confirmed hall orders periodically broadcasted by the master,
+// and acknowledges the hall orders we know of in return (this is how the PrimaryBackup confirms them)
+func (c *Client) handleHallLights() {
+	for {
+		var a HallLightsMsg
+		select {
+		case a = <-c.hallLightsRx:
+		case <-c.ctx.Done():
+			return
+		}
+
+		if c.isIsolated() { // Our lights are handled locally (see handleButtonPress)
+			continue
+		}
+
+		c.setHallLights(a.Orders)
+
+		// Keep the unconfirmed orders, in case we have to take over as master
+		c.mutex_backup.Lock()
+		c.pendingHallOrders = a.Pending
+		c.mutex_backup.Unlock()
+		c.mutex_confirmedHallOrders.Lock()
+		previous := c.confirmedHallOrders
+		c.confirmedHallOrders = a.Orders
+		c.mutex_confirmedHallOrders.Unlock()
+		c.logHallCalls(previous, a.Orders)
+		c.setOutOfService(a.OutOfService)
+		c.applyFireRecall(a.FireRecall, a.RecallFloor)
+		if a.From != c.id { // The master keeps its own, an older message must not undo a change of the API
+			c.setTrafficState(a.TrafficMode, a.TrafficSource, a.TrafficForced)
+		}
+
+		ack := HallOrdersAckMsg{Id: c.id, Role: c.Role(), Orders: c.knownHallOrders()}
+		select {
+		case c.hallOrdersAckTx <- ack:
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Client) handleTurnOffLightsCabOrderCompleted() {
+	for {
+		var a StateMsg
+		select {
+		case a = <-c.localStatesForCabOrders:
+		case <-c.ctx.Done():
+			return
+		}
+
+		var newCabOrders []Order
+
+		// Only keep the cab orders
+		for _, order := range a.State.LocalRequests {
+			if order.OrderType == cab {
+				newCabOrders = append(newCabOrders, order)
+			}
+		}
+
+		// Turn off all the cab lights
+		for f := 0; f < numFloors; f++ {
+			c.driver.SetButtonLamp(elevio.BT_Cab, f, false)
+		}
+
+		// Turn on all the remaining cab orders
+		for _, order := range newCabOrders {
+			c.driver.SetButtonLamp(elevio.BT_Cab, order.Floor, true)
+		}
+	}
+}
+
+func (c *Client) handleTurnOnLightsCabOrder() {
+	for {
+		select {
+		case a := <-c.drv_buttons_forCabLights:
+			if a.Button == elevio.BT_Cab && c.takesCabCalls() && c.servesFloor(a.Floor) { // Else blinkLamp has the lamp
+				c.turnOnCabLights(Order{Floor: a.Floor, Direction: 0, OrderType: cab})
+			}
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Client) handleRetrieveCab() {
+	for {
+		var p CabOrderMsg
+		select {
+		case p = <-c.retrieveCabOrdersRx: // RETRIEVE CAB ORDERS
+		case <-c.ctx.Done():
+			return
+		}
+
+		if p.Id == c.id && !c.isShuttingDown() && c.takesOrders() {
+			for _, order := range p.CabOrders {
+				if !c.servesFloor(order.Floor) { // e.g. taken before we were restarted with other served floors
+					c.logger(logFSM).Infof("Cab order to floor %d dropped, the car does not serve it", order.Floor)
+					continue
+				}
+
+				c.turnOnCabLights(order)
+				// Lock to safely add order and sort
+
+				lockMutexes(&c.mutex_elevatorOrders, &c.mutex_d, &c.mutex_posArray)
+
+				c.addOrder(order)                                 // Add the cab order to the local elevatorOrders
+				sortAllOrders(&c.elevatorOrders, c.d, c.posArray) // Sort the orders
+
+				// Copy the first element locally to avoid holding the mutex longer
+				first_element := c.elevatorOrders[0]
+
+				// Update & send the new state of the elevator to the master
+				c.updateState(c.lastFloor)
+				c.singleStateTx <- StateMsg{c.id, c.latestState}
+
+				unlockMutexes(&c.mutex_elevatorOrders, &c.mutex_d, &c.mutex_posArray)
+
+				// Send to driver outside of mutex lock to prevent blocking
+				c.drv_newOrder <- first_element // Send the first element of the elevatorOrders to the driver
+
+			}
+		}
+	}
+}
+
+func (c *Client) receiveSpamFromMaster() {
+	for {
+		select {
+		case a := <-c.allStatesFromMasterRx: // ALL STATES FROM MASTER
+			myState := a[c.id]
+
+			// Every elevator keeps a copy of all the states, in case it has to take over as master
+			if c.Role() != "Master" {
+				c.mutex_backup.Lock()
+				c.backupStates = a
+				c.mutex_backup.Unlock()
+			}
+
+			if c.takesOrders() { // Our orders were dropped by the fire recall, the master may not know it yet
+				c.mutex_elevatorOrders.Lock()
+				c.elevatorOrders = myState.LocalRequests // Update the local orders array
+				c.mutex_elevatorOrders.Unlock()
+			}
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Client) receiveSpamFromSlave(ctx context.Context) {
+	for {
+		select {
+		case a := <-c.singleStateFromSlaveRx:
+			slaveID := a.Id
+			slaveState := a.State
+
+			c.mutex_backup.Lock()
+			c.backupStates[slaveID] = slaveState // Update the backup states array
+			c.mutex_backup.Unlock()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package elevator
+
+import (
+	"Network-go/network/peers"
+	"reflect"
+	"testing"
+)
+
+func TestAssignRoles(t *testing.T) {
+	tests := []struct {
+		name        string
+		survivors   []peers.ElevIdentity
+		id          int
+		currentRole string
+		want        map[int]string
+	}{
+		{
+			name:        "alone",
+			survivors:   nil,
+			id:          1,
+			currentRole: "Regular",
+			want:        map[int]string{1: "Master"},
+		},
+		{
+			name:        "master and backup survive",
+			survivors:   []peers.ElevIdentity{{Id: 0, Role: "Master"}, {Id: 1, Role: "PrimaryBackup"}, {Id: 2, Role: "Regular"}},
+			id:          2,
+			currentRole: "Regular",
+			want:        map[int]string{0: "Master", 1: "PrimaryBackup", 2: "Regular"},
+		},
+		{
+			name:        "lost master, the backup is promoted",
+			survivors:   []peers.ElevIdentity{{Id: 1, Role: "Regular"}, {Id: 2, Role: "PrimaryBackup"}},
+			id:          1,
+			currentRole: "Regular",
+			want:        map[int]string{2: "Master", 1: "PrimaryBackup"},
+		},
+		{
+			name:        "lost backup, the lowest regular replaces it",
+			survivors:   []peers.ElevIdentity{{Id: 0, Role: "Regular"}, {Id: 1, Role: "Master"}, {Id: 2, Role: "Regular"}},
+			id:          2,
+			currentRole: "Regular",
+			want:        map[int]string{1: "Master", 0: "PrimaryBackup", 2: "Regular"},
+		},
+		{
+			name:        "lost master and backup, the lowest ids take over",
+			survivors:   []peers.ElevIdentity{{Id: 2, Role: "Regular"}, {Id: 1, Role: "Regular"}},
+			id:          2,
+			currentRole: "Regular",
+			want:        map[int]string{1: "Master", 2: "PrimaryBackup"},
+		},
+		{
+			name:        "our own role wins over the one broadcast",
+			survivors:   []peers.ElevIdentity{{Id: 0, Role: "Regular"}, {Id: 2, Role: "Regular"}},
+			id:          2,
+			currentRole: "Master",
+			want:        map[int]string{2: "Master", 0: "PrimaryBackup"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := assignRoles(test.survivors, test.id, test.currentRole); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("assignRoles() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
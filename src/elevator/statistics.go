@@ -0,0 +1,221 @@
+// This file contains the wait time and journey time statistics of the orders, recorded by the master
+package elevator
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The stages of an order that are measured, from the press of its button
+var orderStages = []string{"assignment", "arrival", "doorClose"}
+
+type histogram struct {
+	count   int
+	sum     time.Duration
+	min     time.Duration
+	max     time.Duration
+	buckets []int // buckets[i] counts the durations <= statsBuckets[i], the last one the ones above
+}
+
+// What the master measured, per stage and per floor, direction and elevator
+type orderStatistics struct {
+	mutex      sync.Mutex
+	histograms map[string]*histogram // Keyed by "<stage>,<dimension>,<value>"
+	recorded   map[string]time.Time  // The ids of the orders recorded in the last statsDuplicateWindow (a report may be received twice)
+}
+
+func newOrderStatistics() *orderStatistics {
+	return &orderStatistics{histograms: make(map[string]*histogram), recorded: make(map[string]time.Time)}
+}
+
+func (h *histogram) add(d time.Duration) {
+	if h.count == 0 || d < h.min {
+		h.min = d
+	}
+	if d > h.max {
+		h.max = d
+	}
+	h.count++
+	h.sum += d
+
+	i := sort.Search(len(statsBuckets), func(i int) bool { return d <= statsBuckets[i] })
+	h.buckets[i]++
+}
+
+// Records the orders served by an elevator. The durations are measured from the creation of each order
+func (s *orderStatistics) record(msg OrderServedMsg) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	for id, at := range s.recorded { // A duplicate comes right after the first report, the older ids are dropped
+		if now.Sub(at) > statsDuplicateWindow {
+			delete(s.recorded, id)
+		}
+	}
+
+	for _, order := range msg.Orders {
+		if _, done := s.recorded[order.Id]; order.Id == "" || done || order.CreatedAt.IsZero() {
+			continue
+		}
+		s.recorded[order.Id] = now
+
+		direction := "cab"
+		if order.OrderType == hall && order.Direction == up {
+			direction = "up"
+		} else if order.OrderType == hall {
+			direction = "down"
+		}
+
+		durations := map[string]time.Time{"assignment": order.AssignedAt, "arrival": msg.ArrivedAt, "doorClose": msg.DoorClosedAt}
+		for stage, t := range durations {
+			if t.IsZero() { // Not known (e.g. the door was already open)
+				continue
+			}
+			d := t.Sub(order.CreatedAt)
+			if d < 0 { // The clocks of the elevators are not in sync
+				d = 0
+			}
+			for _, group := range [][2]string{
+				{"all", ""},
+				{"floor", strconv.Itoa(order.Floor)},
+				{"direction", direction},
+				{"elevator", strconv.Itoa(msg.Id)},
+			} {
+				key := stage + "," + group[0] + "," + group[1]
+				h, exists := s.histograms[key]
+				if !exists {
+					h = &histogram{buckets: make([]int, len(statsBuckets)+1)}
+					s.histograms[key] = h
+				}
+				h.add(d)
+			}
+		}
+	}
+}
+
+// OrderHistogram is the distribution of the time between the press of a button and one stage of its order
+// (assignment, arrival of the car or closing of the door), for one floor, direction or elevator.
+// The durations are in seconds
+type OrderHistogram struct {
+	Stage     string // assignment, arrival or doorClose
+	Dimension string // all, floor, direction or elevator
+	Value     string // The floor, the direction (up, down or cab) or the elevator
+	Count     int    // The number of orders
+	Mean      float64
+	Min       float64
+	Max       float64
+	Buckets   []int // Buckets[i] counts the durations up to BucketBounds[i], the last one the longer ones
+}
+
+// OrderStatistics is a snapshot of the statistics recorded by the master
+type OrderStatistics struct {
+	BucketBounds []float64 // The upper bounds of the buckets, in seconds
+	Histograms   []OrderHistogram
+}
+
+// OrderStatistics returns the wait time and journey time statistics recorded while this elevator was master
+func (c *Client) OrderStatistics() OrderStatistics {
+	s := c.orderStats
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stats := OrderStatistics{BucketBounds: []float64{}, Histograms: []OrderHistogram{}}
+	for _, bound := range statsBuckets {
+		stats.BucketBounds = append(stats.BucketBounds, bound.Seconds())
+	}
+	for key, h := range s.histograms {
+		fields := strings.SplitN(key, ",", 3)
+		stats.Histograms = append(stats.Histograms, OrderHistogram{
+			Stage:     fields[0],
+			Dimension: fields[1],
+			Value:     fields[2],
+			Count:     h.count,
+			Mean:      (h.sum / time.Duration(h.count)).Seconds(),
+			Min:       h.min.Seconds(),
+			Max:       h.max.Seconds(),
+			Buckets:   append([]int{}, h.buckets...),
+		})
+	}
+
+	// Sorted by stage (in the order of the journey), then dimension and value
+	stageIndex := func(stage string) int {
+		for i, name := range orderStages {
+			if name == stage {
+				return i
+			}
+		}
+		return len(orderStages)
+	}
+	sort.Slice(stats.Histograms, func(i, j int) bool {
+		a, b := stats.Histograms[i], stats.Histograms[j]
+		if a.Stage != b.Stage {
+			return stageIndex(a.Stage) < stageIndex(b.Stage)
+		}
+		if a.Dimension != b.Dimension {
+			return a.Dimension < b.Dimension
+		}
+		return a.Value < b.Value
+	})
+	return stats
+}
+
+// WriteJSON writes the statistics as JSON
+func (s OrderStatistics) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
+
+// WriteCSV writes the statistics as CSV, one line per histogram
+func (s OrderStatistics) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{"stage", "dimension", "value", "count", "mean_s", "min_s", "max_s"}
+	for _, bound := range s.BucketBounds {
+		header = append(header, "le_"+strconv.FormatFloat(bound, 'f', -1, 64)+"s")
+	}
+	header = append(header, "le_inf")
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	formatSeconds := func(f float64) string { return strconv.FormatFloat(f, 'f', 3, 64) }
+	for _, h := range s.Histograms {
+		line := []string{h.Stage, h.Dimension, h.Value, strconv.Itoa(h.Count), formatSeconds(h.Mean), formatSeconds(h.Min), formatSeconds(h.Max)}
+		for _, count := range h.Buckets {
+			line = append(line, strconv.Itoa(count))
+		}
+		if err := writer.Write(line); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// Summary returns a few lines with the overall wait and journey times
+func (s OrderStatistics) Summary() string {
+	var b strings.Builder
+	b.WriteString("Order statistics (from the press of the button):\n")
+	found := false
+	for _, h := range s.Histograms {
+		if h.Dimension != "all" {
+			continue
+		}
+		found = true
+		fmt.Fprintf(&b, "  %-10s %4d orders, mean %6.1fs, min %6.1fs, max %6.1fs\n", h.Stage, h.Count, h.Mean, h.Min, h.Max)
+	}
+	if !found {
+		b.WriteString("  no order was served while this elevator was master\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,76 @@
+package elevator
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRecordOrderStatistics(t *testing.T) {
+	createdAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local)
+	// Returns the report of elevator 1 serving an order id, its car arriving after a while
+	served := func(id string, after time.Duration) OrderServedMsg {
+		order := Order{Floor: 2, OrderType: cab, Id: id, CreatedAt: createdAt}
+		return OrderServedMsg{Id: 1, Orders: []Order{order}, ArrivedAt: createdAt.Add(after)}
+	}
+	// Returns the buckets of the arrival histogram, with counts[i] durations in bucket i
+	buckets := func(counts map[int]int) []int {
+		b := make([]int, len(statsBuckets)+1)
+		for i, count := range counts {
+			b[i] = count
+		}
+		return b
+	}
+	forget := func(s *orderStatistics) { // The ids were recorded longer than statsDuplicateWindow ago
+		for id := range s.recorded {
+			s.recorded[id] = time.Now().Add(-2 * statsDuplicateWindow)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		reports     []OrderServedMsg
+		between     func(s *orderStatistics) // Called between the reports, if any
+		wantCount   int
+		wantBuckets []int
+	}{
+		{"one order", []OrderServedMsg{served("a", 3*time.Second)}, nil, 1, buckets(map[int]int{2: 1})},
+		{"on the bound of a bucket", []OrderServedMsg{served("a", 5*time.Second)}, nil, 1, buckets(map[int]int{2: 1})},
+		{"above the last bound", []OrderServedMsg{served("a", 10*time.Minute)}, nil, 1, buckets(map[int]int{len(statsBuckets): 1})},
+		{"before its creation, the clocks are not in sync", []OrderServedMsg{served("a", -time.Second)}, nil, 1, buckets(map[int]int{0: 1})},
+		{"two orders", []OrderServedMsg{served("a", 3*time.Second), served("b", 15*time.Second)}, nil, 2, buckets(map[int]int{2: 1, 4: 1})},
+		{"a report received twice is counted once", []OrderServedMsg{served("a", 3*time.Second), served("a", 3*time.Second)}, nil, 1, buckets(map[int]int{2: 1})},
+		{"an id is forgotten after a while", []OrderServedMsg{served("a", 3*time.Second), served("a", 3*time.Second)}, forget, 2, buckets(map[int]int{2: 2})},
+		{"an order without id is not counted", []OrderServedMsg{served("", 3*time.Second)}, nil, 0, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newOrderStatistics()
+			for i, report := range test.reports {
+				if i > 0 && test.between != nil {
+					test.between(s)
+				}
+				s.record(report)
+			}
+
+			h, exists := s.histograms["arrival,all,"]
+			if test.wantCount == 0 {
+				if exists {
+					t.Errorf("arrival histogram = %+v, want none", *h)
+				}
+				return
+			}
+			if !exists {
+				t.Fatalf("no arrival histogram, want %d orders", test.wantCount)
+			}
+			if h.count != test.wantCount || !reflect.DeepEqual(h.buckets, test.wantBuckets) {
+				t.Errorf("arrival histogram: count %d, buckets %v, want count %d, buckets %v", h.count, h.buckets, test.wantCount, test.wantBuckets)
+			}
+			for _, key := range []string{"arrival,floor,2", "arrival,direction,cab", "arrival,elevator,1"} {
+				if s.histograms[key] == nil || s.histograms[key].count != test.wantCount {
+					t.Errorf("histogram %s does not count the %d orders", key, test.wantCount)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+// This file contains the traffic modes of the building. The master counts the hall calls of a sliding window: in
+// the morning up-peak most of them go up from the lobby, in the evening down-peak most of them go down from the
+// upper floors. During an up-peak the idle cars wait at the lobby and its calls are split among them, during a
+// down-peak they wait spread over the upper floors. The mode can also come from a schedule (Config.TrafficSchedule)
+// or be forced through the API, which wins over both
+package elevator
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Parses Config.TrafficSchedule, e.g. "07:30-09:30=upPeak,16:30-18:00=downPeak"
+func parseTrafficSchedule(schedule string) ([]trafficPeriod, error) {
+	periods := []trafficPeriod{}
+	for _, entry := range strings.Split(schedule, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("The traffic schedule entry %q must be <start>-<end>=<mode>", entry)
+		}
+		mode := strings.TrimSpace(parts[1])
+		if mode != trafficNormal && mode != trafficUpPeak && mode != trafficDownPeak {
+			return nil, fmt.Errorf("The traffic mode %q must be normal, upPeak or downPeak", mode)
+		}
+		hours := strings.SplitN(parts[0], "-", 2)
+		if len(hours) != 2 {
+			return nil, fmt.Errorf("The traffic schedule entry %q must be <start>-<end>=<mode>", entry)
+		}
+		start, err := time.Parse("15:04", strings.TrimSpace(hours[0]))
+		if err != nil {
+			return nil, fmt.Errorf("The traffic schedule entry %q: %v", entry, err)
+		}
+		end, err := time.Parse("15:04", strings.TrimSpace(hours[1]))
+		if err != nil {
+			return nil, fmt.Errorf("The traffic schedule entry %q: %v", entry, err)
+		}
+		periods = append(periods, trafficPeriod{Start: sinceMidnight(start), End: sinceMidnight(end), Mode: mode})
+	}
+	return periods, nil
+}
+
+func sinceMidnight(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+}
+
+// Returns the traffic mode of the schedule at this time, empty if no period covers it
+func scheduledTrafficMode(schedule []trafficPeriod, now time.Time) string {
+	t := sinceMidnight(now)
+	for _, period := range schedule {
+		if period.Start <= period.End && t >= period.Start && t < period.End ||
+			period.Start > period.End && (t >= period.Start || t < period.End) { // Past midnight
+			return period.Mode
+		}
+	}
+	return ""
+}
+
+// Drops the calls that left the window. Returns the other ones and their rate (calls per minute) at each floor,
+// up and down
+func trafficRates(calls []trafficCall, now time.Time) ([]trafficCall, [][2]float64) {
+	recent := []trafficCall{}
+	rates := make([][2]float64, numFloors)
+	for _, call := range calls {
+		if now.Sub(call.At) > trafficWindow {
+			continue
+		}
+		recent = append(recent, call)
+		if call.Direction == up {
+			rates[call.Floor][0] += 1 / trafficWindow.Minutes()
+		} else {
+			rates[call.Floor][1] += 1 / trafficWindow.Minutes()
+		}
+	}
+	return recent, rates
+}
+
+// Detects the traffic mode from the calls of the window. A peak ends at a lower share of the calls than the one it
+// starts at, so that the mode does not flap
+func detectTrafficMode(calls []trafficCall, lobby int, current string) string {
+	if len(calls) < trafficMinCalls {
+		return trafficNormal
+	}
+	upFromLobby, downToLobby := 0, 0
+	for _, call := range calls {
+		if call.Floor == lobby && call.Direction == up {
+			upFromLobby++
+		} else if call.Floor > lobby && call.Direction == down {
+			downToLobby++
+		}
+	}
+	upShare := float64(upFromLobby) / float64(len(calls))
+	downShare := float64(downToLobby) / float64(len(calls))
+
+	switch {
+	case current == trafficUpPeak && upShare >= trafficEndShare, upShare >= trafficPeakShare:
+		return trafficUpPeak
+	case current == trafficDownPeak && downShare >= trafficEndShare, downShare >= trafficPeakShare:
+		return trafficDownPeak
+	}
+	return trafficNormal
+}
+
+// Returns the traffic mode of the building, where it comes from and the mode forced through the API (if any)
+func (c *Client) trafficState() (string, string, string) {
+	c.mutex_traffic.Lock()
+	defer c.mutex_traffic.Unlock()
+	return c.trafficMode, c.trafficSource, c.trafficForced
+}
+
+func (c *Client) currentTrafficMode() string {
+	mode, _, _ := c.trafficState()
+	return mode
+}
+
+// Keeps the traffic mode broadcast by the master (see handleHallLights)
+func (c *Client) setTrafficState(mode, source, forced string) {
+	c.mutex_traffic.Lock()
+	defer c.mutex_traffic.Unlock()
+	c.trafficMode, c.trafficSource, c.trafficForced = mode, source, forced
+}
+
+// Forces a traffic mode (for the master), or goes back to the schedule and the detection if mode is empty
+func (c *Client) forceTrafficMode(mode string) {
+	c.mutex_traffic.Lock()
+	c.trafficForced = mode
+	c.mutex_traffic.Unlock()
+	if mode == "" {
+		c.logEvent(logMaster, "Traffic mode: back to the schedule and the detection")
+	} else {
+		c.logEvent(logMaster, "Traffic mode: %s forced through the API", mode)
+	}
+}
+
+// Updates the traffic mode (for the master): the one forced through the API, else the one of the schedule, else
+// the detected one. Returns it
+func (c *Client) updateTrafficMode(detected string, rates [][2]float64) string {
+	c.mutex_traffic.Lock()
+	previous := c.trafficMode
+	mode, source := detected, "detected"
+	if scheduled := scheduledTrafficMode(c.trafficSchedule, time.Now()); scheduled != "" {
+		mode, source = scheduled, "schedule"
+	}
+	if c.trafficForced != "" {
+		mode, source = c.trafficForced, "api"
+	}
+	c.trafficMode, c.trafficSource, c.trafficRates = mode, source, rates
+	c.mutex_traffic.Unlock()
+
+	if mode != previous {
+		c.logEvent(logMaster, "Traffic mode: %s (%s)", mode, source)
+	}
+	return mode
+}
+
+// Returns the cars that can be moved to wait somewhere else: in the group dispatch, idle, without orders and with
+// their door closed
+func (c *Client) idleCars(allStates [numElev]ElevState) []int {
+	idle := []int{}
+	for _, id := range c.candidateElevators(allStates, -1) {
+		state := allStates[id]
+		if state.Behavior == "idle" && len(state.LocalRequests) == 0 && !state.DoorOpen && dispatchable(state.Mode) {
+			idle = append(idle, id)
+		}
+	}
+	return idle
+}
+
+// Returns the floors where the idle cars wait in a traffic mode (id -> floor), except the cars that are already
+// there. During a down-peak they are spread evenly over the upper floors
+func parkingFloors(allStates [numElev]ElevState, idle []int, mode string, lobby int) map[int]int {
+	floors := make(map[int]int)
+	switch mode {
+	case trafficUpPeak:
+		for _, id := range idle {
+			floors[id] = lobby
+		}
+	case trafficDownPeak:
+		if lobby < numFloors-1 {
+			floors = spreadOver(allStates, idle, lobby+1, numFloors-1)
+		}
+	}
+	return notThereYet(allStates, floors)
+}
+
+// During an up-peak the calls going up from the lobby are split among the cars waiting there: a call goes to the
+// one that took a lobby call the longest ago (lastLobbyCalls). Returns the candidates if no car waits there
+func lobbyCandidates(allStates [numElev]ElevState, candidates []int, lobby int, lastLobbyCalls [numElev]time.Time) []int {
+	waiting := -1
+	for _, id := range candidates {
+		state := allStates[id]
+		if state.Floor != lobby || state.Behavior != "idle" || len(state.LocalRequests) > 0 {
+			continue
+		}
+		if waiting == -1 || lastLobbyCalls[id].Before(lastLobbyCalls[waiting]) {
+			waiting = id
+		}
+	}
+	if waiting == -1 {
+		return candidates
+	}
+	return []int{waiting}
+}
@@ -0,0 +1,113 @@
+package elevator
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTrafficSchedule(t *testing.T) {
+	tests := []struct {
+		schedule string
+		want     []trafficPeriod
+		wantErr  bool
+	}{
+		{schedule: "", want: []trafficPeriod{}},
+		{
+			schedule: "07:30-09:30=upPeak, 16:30-18:00=downPeak",
+			want: []trafficPeriod{
+				{Start: 7*time.Hour + 30*time.Minute, End: 9*time.Hour + 30*time.Minute, Mode: trafficUpPeak},
+				{Start: 16*time.Hour + 30*time.Minute, End: 18 * time.Hour, Mode: trafficDownPeak},
+			},
+		},
+		{
+			schedule: "22:00-02:00=normal,",
+			want:     []trafficPeriod{{Start: 22 * time.Hour, End: 2 * time.Hour, Mode: trafficNormal}},
+		},
+		{schedule: "07:30=upPeak", wantErr: true},
+		{schedule: "07:30-09:30", wantErr: true},
+		{schedule: "07:30-09:30=rush", wantErr: true},
+		{schedule: "7h30-09:30=upPeak", wantErr: true},
+		{schedule: "07:30-25:00=upPeak", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.schedule, func(t *testing.T) {
+			got, err := parseTrafficSchedule(test.schedule)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("parseTrafficSchedule() error = %v, want error %v", err, test.wantErr)
+			}
+			if !test.wantErr && !reflect.DeepEqual(got, test.want) {
+				t.Errorf("parseTrafficSchedule() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestScheduledTrafficMode(t *testing.T) {
+	schedule := []trafficPeriod{
+		{Start: 7*time.Hour + 30*time.Minute, End: 9*time.Hour + 30*time.Minute, Mode: trafficUpPeak},
+		{Start: 22 * time.Hour, End: 2 * time.Hour, Mode: trafficDownPeak}, // Past midnight
+	}
+	at := func(hour, minute int) time.Time { return time.Date(2024, 3, 1, hour, minute, 0, 0, time.Local) }
+	tests := []struct {
+		name string
+		now  time.Time
+		want string
+	}{
+		{"before the morning", at(7, 29), ""},
+		{"start of the morning", at(7, 30), trafficUpPeak},
+		{"end of the morning", at(9, 30), ""},
+		{"evening", at(23, 0), trafficDownPeak},
+		{"midnight", at(0, 0), trafficDownPeak},
+		{"past midnight", at(1, 59), trafficDownPeak},
+		{"end of the night", at(2, 0), ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := scheduledTrafficMode(schedule, test.now); got != test.want {
+				t.Errorf("scheduledTrafficMode(%v) = %q, want %q", test.now.Format("15:04"), got, test.want)
+			}
+		})
+	}
+}
+
+func TestDetectTrafficMode(t *testing.T) {
+	const lobby = 0
+	// Returns the calls of a window: some going up from the lobby, some going down to it, and the other ones
+	calls := func(upFromLobby, downToLobby, others int) []trafficCall {
+		window := []trafficCall{}
+		for i := 0; i < upFromLobby; i++ {
+			window = append(window, trafficCall{Floor: lobby, Direction: up})
+		}
+		for i := 0; i < downToLobby; i++ {
+			window = append(window, trafficCall{Floor: numFloors - 1, Direction: down})
+		}
+		for i := 0; i < others; i++ {
+			window = append(window, trafficCall{Floor: 1, Direction: up})
+		}
+		return window
+	}
+	tests := []struct {
+		name    string
+		calls   []trafficCall
+		current string
+		want    string
+	}{
+		{"too few calls", calls(trafficMinCalls-1, 0, 0), trafficNormal, trafficNormal},
+		{"too few calls end a peak", calls(trafficMinCalls-1, 0, 0), trafficUpPeak, trafficNormal},
+		{"up-peak starts", calls(6, 0, 4), trafficNormal, trafficUpPeak},
+		{"below the start share", calls(5, 0, 5), trafficNormal, trafficNormal},
+		{"up-peak goes on above the end share", calls(5, 0, 5), trafficUpPeak, trafficUpPeak},
+		{"up-peak ends below the end share", calls(3, 0, 7), trafficUpPeak, trafficNormal},
+		{"down-peak starts", calls(0, 7, 3), trafficNormal, trafficDownPeak},
+		{"down-peak goes on above the end share", calls(0, 4, 6), trafficDownPeak, trafficDownPeak},
+		{"an up-peak share does not keep a down-peak", calls(5, 0, 5), trafficDownPeak, trafficNormal},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := detectTrafficMode(test.calls, lobby, test.current); got != test.want {
+				t.Errorf("detectTrafficMode() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
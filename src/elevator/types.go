@@ -0,0 +1,247 @@
+// This file contains the type declarations for the client
+package elevator
+
+import (
+	"Driver-go/elevio"
+	"Network-go/network/peers"
+	"context"
+	"fmt"
+	"time"
+)
+
+type ElevState struct { // Struct for the state of the elevator
+	Behavior      string  // 'moving' or 'idle'
+	Floor         int     // The floor the elevator is at
+	Direction     string  // 'up', 'down' or 'stop'
+	LocalRequests []Order // The requests of the elevator
+	DoorOpen      bool    // Whether the door is open
+	Mode          string  // normal, fireRecall, firefighter, independent or inspection (see globalVariables.go)
+	ServedFloors  []int   // The floors the elevator stops at, empty for all of them (see zoning.go)
+}
+
+type HRAInput struct {
+	HallRequests []Order
+	States       map[string]ElevState
+}
+
+type HallOrderMsg struct {
+	Id        int
+	HallOrder Order
+}
+
+type CabOrderMsg struct {
+	Id        int
+	CabOrders []Order
+}
+
+type HallLightsMsg struct { // Structure used by the master to broadcast the hall orders whose lights must be on
+	From          int     // The id of the master
+	Orders        []Order // The confirmed (and assigned) hall orders
+	Pending       []Order // The hall orders waiting for a confirmation
+	OutOfService  []int   // The elevators put out of service (no hall orders are assigned to them), kept by every elevator
+	FireRecall    bool    // The fire recall (Phase I) is on: every car goes to RecallFloor, the hall buttons are ignored
+	RecallFloor   int
+	TrafficMode   string // The traffic mode of the building: normal, upPeak or downPeak (see traffic.go)
+	TrafficSource string // Where the traffic mode comes from: detected, schedule or api
+	TrafficForced string // The traffic mode forced through the API, empty if none. Kept by every elevator
+}
+
+type FireRecallMsg struct { // Structure used to turn the fire recall on or off (fire recall switch or API), sent to the master
+	From   int
+	Active bool
+}
+
+type InspectionMsg struct { // Structure used to send a command of the inspection mode to an elevator (elevctl inspect)
+	Id      int    // The elevator
+	Command string // on, off, up, down or stop
+	Seq     string // Unique per command: a command sent again is only applied once
+}
+
+type InspectionAckMsg struct { // Structure used by an elevator to answer an InspectionMsg
+	From  int
+	Seq   string
+	Error string // Empty if the command was applied
+	State ElevState
+}
+
+type ParkMsg struct { // Structure used by the master to send an idle car to a floor, where it waits with its door closed
+	Id    int // The elevator
+	Floor int
+}
+
+type DestinationCallMsg struct { // Structure used by an elevator to send a destination entered at its hall to the master
+	Id          string // Unique in the cluster, see newOrder
+	Origin      int    // The elevator whose hall panel it was entered on
+	Floor       int
+	Destination int
+}
+
+type DestinationAssignedMsg struct { // Structure used by the master to tell the hall panels which car takes a group
+	Id           string // The destination call it answers (DestinationCallMsg.Id)
+	Floor        int    // Where the group is picked up
+	Destinations []int  // The floors of its passengers
+	Elevator     int    // The car to take, shown on the hall panels. -1 if no car can take the call
+}
+
+type CancelOrderMsg struct { // Structure used by the master to cancel an order
+	Id    int // The elevator that must drop it, -1 for every elevator (hall orders)
+	Order Order
+}
+
+type masterCommand struct { // A command given to the master through the API (see api.go)
+	Kind      string // cancel, service or traffic
+	OrderId   string // cancel: the id of the order
+	Elevator  int    // service: the elevator
+	InService bool   // service: put it in or out of service
+	Mode      string // traffic: the traffic mode to force, auto to go back to the schedule and the detection
+	reply     chan error
+}
+
+type HallOrdersAckMsg struct { // Structure used to acknowledge the hall orders we know of
+	Id     int
+	Role   string // The confirmation of the PrimaryBackup is the one that counts
+	Orders []Order
+}
+
+type StateMsg struct { // Structure used to send states to the master
+	Id    int
+	State ElevState
+}
+
+type OrderServedMsg struct { // Structure used to tell the master when the orders were served, for the statistics
+	Id           int       // The elevator that served them
+	Orders       []Order   // The orders served at this stop
+	ArrivedAt    time.Time // When the car stopped at the floor
+	DoorClosedAt time.Time // When the door closed again (zero if unknown)
+}
+
+type LeaveMsg struct { // Structure used by an elevator to announce that it is shutting down
+	Id         int
+	Role       string             // The role that must be taken over
+	HallOrders []Order            // The hall orders that must be re-assigned
+	States     [numElev]ElevState // The final snapshot of the states
+}
+
+type LeaveAckMsg struct { // Structure used to acknowledge a LeaveMsg
+	Id   int // The id of the elevator that is leaving
+	From int // The id of the elevator that acknowledges
+}
+
+type RejoinMsg struct { // Structure used by an elevator that comes back after a network partition
+	Id     int
+	Role   string             // The role it had while it was isolated
+	Orders []Order            // Its local elevatorOrders
+	Served []Order            // The hall orders it served while it was isolated
+	States [numElev]ElevState // Its last known states of the cluster
+}
+
+type RejoinAckMsg struct { // Structure used by the master to acknowledge a RejoinMsg
+	Id   int    // The id of the elevator that rejoins
+	From int    // The id of the master
+	Role string // The role the elevator must take in the cluster
+}
+
+type ButtonType int // Enum for the button types
+
+type ButtonEvent struct { // Struct for the button events
+	Floor  int
+	Button ButtonType
+}
+
+type OrderDirection int // Enum for the order directions
+
+type OrderType int // Enum for the order types
+
+type HallOrderStatus int // Enum for the lifecycle of a hall order (kept by the master)
+
+type hallOrderRecord struct { // What the master knows about a hall order
+	Order      Order // The order itself, with its id and assignment history
+	Status     HallOrderStatus
+	Elevator   int           // The elevator it is assigned to
+	AssignedAt time.Time     // When it was (last) assigned
+	Estimate   time.Duration // The time it should take to serve it, when it was assigned
+}
+
+type Order struct { // Struct for the orders
+	Floor     int
+	Direction OrderDirection // 1 for up, -1 for down
+	OrderType OrderType      // 0 for hall, 1 for cab
+	Group     string         // Destination dispatch: the group of passengers picked up by a hall order, empty otherwise
+
+	// Tracking of the order, it does not take part in the comparisons of orders (see key)
+	Id         string    // Unique in the cluster, see newOrder
+	Origin     int       // The elevator whose button was pressed
+	CreatedAt  time.Time // When the button was pressed
+	AssignedAt time.Time // When it was first assigned to an elevator
+	History    []int     // The last elevators it was assigned to (at most maxOrderHistory), the last one being the current one
+
+	// Destination dispatch: the floors the passengers of the group go to, taken as cab orders once they are picked
+	// up (see destinationDispatch.go)
+	Destinations []int
+}
+
+type orderKey struct { // Identifies the button of an order: two presses of the same button are the same order
+	Floor     int
+	Direction OrderDirection
+	OrderType OrderType
+	Group     string // Several cars can pick up their own group at the same floor
+}
+
+func (o Order) key() orderKey {
+	return orderKey{o.Floor, o.Direction, o.OrderType, o.Group}
+}
+
+// SameButton tells whether two orders are for the same button (their ids may differ), and for the same group of
+// passengers with the destination dispatch
+func (o Order) SameButton(other Order) bool {
+	return o.key() == other.key()
+}
+
+// Name returns the button of the order, e.g. "up 2" or "cab 0"
+func (o Order) Name() string {
+	if o.OrderType == cab {
+		return fmt.Sprintf("cab %d", o.Floor)
+	}
+	return fmt.Sprintf("%s %d", directionName(o.Direction), o.Floor)
+}
+
+type trafficCall struct { // A hall call, as counted by the master for the traffic detection
+	At        time.Time
+	Floor     int
+	Direction OrderDirection
+}
+
+type trafficPeriod struct { // A period of Config.TrafficSchedule
+	Start time.Duration // Since midnight
+	End   time.Duration // Since midnight, before Start if the period goes past midnight
+	Mode  string
+}
+
+type elevatorActivity struct {
+	id            int
+	timestamp     time.Time
+	localRequests []Order
+}
+
+// Driver is the interface to the hardware (or simulator) of a single elevator car. It is satisfied by *elevio.Driver
+type Driver interface {
+	SetMotorDirection(dir elevio.MotorDirection)
+	SetButtonLamp(button elevio.ButtonType, floor int, value bool)
+	SetFloorIndicator(floor int)
+	SetDoorOpenLamp(value bool)
+	SetStopLamp(value bool)
+	PollButtons(receiver chan<- elevio.ButtonEvent)
+	PollFloorSensor(receiver chan<- int)
+	PollFloorSensor2(receiver chan<- int)
+	PollStopButton(receiver chan<- bool)
+	PollObstructionSwitch(receiver chan<- bool)
+}
+
+// Transport is the network layer used to talk to the other elevators. See UDPTransport for the default one
+// All the functions block until ctx is cancelled, and must release their sockets before returning
+type Transport interface {
+	Transmitter(ctx context.Context, port int, chans ...interface{})                                           // Broadcast the values received on chans
+	Receiver(ctx context.Context, port int, chans ...interface{})                                              // Decode the values received and send them on chans
+	PeerTransmitter(ctx context.Context, port int, id int, roleChan <-chan string, transmitEnable <-chan bool) // Broadcast our identity
+	PeerReceiver(ctx context.Context, port int, peerUpdateCh chan<- peers.PeerUpdate)                          // Listen for peer updates
+}
@@ -0,0 +1,556 @@
+package elevator
+
+import (
+	"Driver-go/elevio"
+	"Network-go/network/logging"
+	"Network-go/network/peers"
+	"fmt"
+	"sync"
+	"time"
+)
+
+func (c *Client) getNearestFloor() int {
+	// Assuming we are idle, get the nearest floor accessible
+	// This function is here to make sure we don't try to go below 0 or above numFloors
+
+	c.mutex_posArray.Lock()
+	currentFloor := c.extractPos()
+	c.mutex_posArray.Unlock()
+
+	floorIndex := int(currentFloor)
+
+	if floorIndex == 0 {
+		return 1
+	}
+	if floorIndex == numFloors-1 {
+		return numFloors - 2
+	}
+
+	return floorIndex + 1
+}
+
+func initAllStates(allStates [numElev]ElevState) [numElev]ElevState {
+	uninitializedOrderArray := []Order{
+		{
+			Floor:     0,
+			Direction: up,
+			OrderType: hall,
+		},
+	}
+	uninitialized_ElevState := ElevState{
+		Behavior:      "Uninitialized",
+		Floor:         -2,
+		Direction:     "Uninitialized",
+		LocalRequests: uninitializedOrderArray,
+	}
+
+	for i := range allStates {
+		allStates[i] = uninitialized_ElevState
+	}
+
+	return allStates
+}
+
+func sortElevators(activeElevators []int) []int {
+	// Sort the active elevators
+	for i := 0; i < len(activeElevators); i++ {
+		for j := i + 1; j < len(activeElevators); j++ {
+			if activeElevators[i] > activeElevators[j] {
+				activeElevators[i], activeElevators[j] = activeElevators[j], activeElevators[i]
+			}
+		}
+	}
+	return activeElevators
+}
+
+func lockMutexes(mutexes ...*sync.Mutex) { // Locks multiple mutexes
+	for _, m := range mutexes {
+		m.Lock()
+	}
+}
+
+func unlockMutexes(mutexes ...*sync.Mutex) { // Unlocks multiple mutexes
+	for _, m := range mutexes {
+		m.Unlock()
+	}
+}
+
+func (c *Client) isElevatorActive(elevatorId int) bool {
+	// Check if the elevator is active
+	for _, id := range c.activeElevators {
+		if id == elevatorId {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Client) removeElevator(elevatorId int) {
+	// Removes the id of the elevator from the list of active elevators
+	for i, id := range c.activeElevators {
+		if id == elevatorId {
+			c.activeElevators = append(c.activeElevators[:i], c.activeElevators[i+1:]...)
+			break // Exit to avoid issues with changed indices (works because ids are unique)
+		}
+	}
+}
+
+// Tells whether the master gives hall orders to a car in this mode ("" is the state of an elevator not heard yet)
+func dispatchable(mode string) bool {
+	return mode == modeNormal || mode == ""
+}
+
+func isPeer(peerList []peers.ElevIdentity, elevatorId int) bool {
+	// Check if the elevator is in the list of peers
+	for _, peer := range peerList {
+		if peer.Id == elevatorId {
+			return true
+		}
+	}
+	return false
+}
+
+func removeDuplicateOrders(orders []Order) []Order {
+	// Keep only one copy of each order (e.g. the same hall order held by two lost elevators)
+	seen := make(map[orderKey]bool)
+	var uniqueOrders []Order
+	for _, order := range orders {
+		if !seen[order.key()] {
+			seen[order.key()] = true
+			uniqueOrders = append(uniqueOrders, order)
+		}
+	}
+	return uniqueOrders
+}
+
+func btnPressToOrder(btn elevio.ButtonEvent) Order { // Convert a button press to an order for hall orders
+	orderType := hall
+	orderDirection := up
+	if btn.Button == elevio.BT_HallDown {
+		orderDirection = down
+	}
+	return Order{Floor: btn.Floor, Direction: orderDirection, OrderType: orderType}
+}
+
+// NewHallCall returns a hall order pressed from outside of the cluster (e.g. by elevctl), to be sent on
+// HallOrderRawBTN_PORT. Its origin is -1 and its id starts with source
+func NewHallCall(floor int, goingUp bool, source string) Order {
+	direction := down
+	if goingUp {
+		direction = up
+	}
+	now := time.Now()
+	return Order{Floor: floor, Direction: direction, OrderType: hall, Id: fmt.Sprintf("%s-%d", source, now.UnixNano()), Origin: -1, CreatedAt: now}
+}
+
+// Returns a new id, unique in the cluster: <our id>-<our start time>-<sequence number>
+func (c *Client) nextOrderId() string {
+	c.mutex_orderIds.Lock()
+	defer c.mutex_orderIds.Unlock()
+	c.orderSequence++
+	return fmt.Sprintf("%d-%d-%d", c.id, c.startedAt.UnixNano(), c.orderSequence)
+}
+
+// Stamps an order created by a button of our panel with a new id, its origin and its creation time
+func (c *Client) newOrder(order Order) Order {
+	order.Id = c.nextOrderId()
+	order.Origin = c.id
+	order.CreatedAt = time.Now()
+	return order
+}
+
+// Returns a copy of the order with one more elevator in its assignment history
+func (o Order) assignedTo(id int) Order {
+	if o.AssignedAt.IsZero() {
+		o.AssignedAt = time.Now()
+	}
+	o.History = append(append([]int{}, o.History...), id)
+	if len(o.History) > maxOrderHistory { // Re-assigned again and again (e.g. by the watchdog), the oldest ones go
+		o.History = o.History[len(o.History)-maxOrderHistory:]
+	}
+	return o
+}
+
+func elevDirectionToElevioButtonType(Direction OrderDirection) (buttonType elevio.ButtonType) {
+	// 1 for up, -1 for down
+	/* const (
+		BT_HallUp   ButtonType = 0
+		BT_HallDown            = 1
+		BT_Cab                 = 2
+	) */
+	switch Direction {
+	case 1:
+		return elevio.BT_HallUp
+	case -1:
+		return elevio.BT_HallDown
+	default:
+		logging.New(logFSM).Errorf("Invalid direction %d passed to elevDirectionToElevioButtonType", Direction)
+	}
+
+	return
+}
+
+func determineBehaviour(d *elevio.MotorDirection) string { // Determine the behaviour of the elevator based on its direction
+	switch {
+	case *d == elevio.MD_Stop:
+		return "idle"
+	case *d == elevio.MD_Up || *d == elevio.MD_Down:
+		return "moving"
+	}
+	return "unknown"
+}
+
+func motorDirectionToString(d elevio.MotorDirection) string { // Convert the motor direction to a string
+	switch {
+	case d == elevio.MD_Up:
+		return "up"
+	case d == elevio.MD_Down:
+		return "down"
+	case d == elevio.MD_Stop:
+		return "stop"
+	}
+	return "unknown"
+}
+
+func (c *Client) updateState(lastFloor int) { // Update the state of the elevator
+	inspection := c.currentMode() == modeInspection
+	c.mutex_state.Lock()
+	defer c.mutex_state.Unlock()
+
+	c.latestState.Behavior = determineBehaviour(&c.d)
+	if inspection {
+		c.latestState.Behavior = behaviourInspection
+	}
+	c.latestState.Floor = lastFloor
+	c.latestState.Direction = motorDirectionToString(c.d)
+	c.latestState.LocalRequests = c.elevatorOrders
+}
+
+// Opens or closes the door (its lamp) and keeps it in our state
+func (c *Client) setDoorOpen(open bool) {
+	c.driver.SetDoorOpenLamp(open)
+	c.mutex_state.Lock()
+	c.latestState.DoorOpen = open
+	c.mutex_state.Unlock()
+}
+
+func (c *Client) turnOffHallLights(orders ...Order) {
+	// Turn off the button lamp at the current floor
+	for _, order := range orders {
+		if order.OrderType == hall { // Hall button
+			if order.Direction == up { // Hall up
+				c.driver.SetButtonLamp(elevio.BT_HallUp, order.Floor, false)
+			} else { // Hall down
+				c.driver.SetButtonLamp(elevio.BT_HallDown, order.Floor, false)
+			}
+		}
+	}
+
+}
+
+func (c *Client) turnOffCabLights(orders ...Order) { // Turn off the lights for the current order
+	for _, order := range orders {
+		if order.OrderType == cab {
+			c.driver.SetButtonLamp(elevio.BT_Cab, order.Floor, false)
+		}
+	}
+
+}
+
+func (c *Client) turnOffAllLights() {
+	for f := 0; f < numFloors; f++ {
+		for b := ButtonType(0); b < 3; b++ {
+			c.driver.SetButtonLamp(elevio.ButtonType(b), f, false)
+		}
+	}
+}
+
+func (c *Client) turnOnCabLights(orders ...Order) {
+	for _, order := range orders {
+		if order.OrderType == cab {
+			c.driver.SetButtonLamp(elevio.ButtonType(BT_Cab), order.Floor, true)
+		}
+	}
+}
+
+// Turns on the lights of the given hall orders and turns off all the other hall lights
+func (c *Client) setHallLights(orders []Order) {
+	var lit [numFloors][2]bool
+	for _, order := range orders {
+		if order.OrderType == hall && order.Floor >= 0 && order.Floor < numFloors {
+			lit[order.Floor][elevDirectionToElevioButtonType(order.Direction)] = true
+		}
+	}
+
+	for f := 0; f < numFloors; f++ {
+		c.driver.SetButtonLamp(elevio.BT_HallUp, f, lit[f][elevio.BT_HallUp])
+		c.driver.SetButtonLamp(elevio.BT_HallDown, f, lit[f][elevio.BT_HallDown])
+	}
+}
+
+// Returns the hall orders we know of: our own ones, the ones in our copy of the states and the unconfirmed ones
+func (c *Client) knownHallOrders() []Order {
+	c.mutex_elevatorOrders.Lock()
+	known := extractHallOrders(c.elevatorOrders)
+	c.mutex_elevatorOrders.Unlock()
+
+	c.mutex_backup.Lock()
+	known = append(known, c.pendingHallOrders...)
+	for _, state := range c.backupStates {
+		if state.Behavior != "Uninitialized" {
+			known = append(known, extractHallOrders(state.LocalRequests)...)
+		}
+	}
+	c.mutex_backup.Unlock()
+
+	return removeDuplicateOrders(known)
+}
+
+func (c *Client) turnOnHallLights(orders ...Order) {
+	for _, order := range orders {
+		if order.OrderType == hall {
+			hallOrderDir := order.Direction
+			buttonType := elevDirectionToElevioButtonType(hallOrderDir)
+			c.driver.SetButtonLamp(buttonType, order.Floor, true)
+		}
+
+	}
+}
+
+func (c *Client) trackPosition() { // Track the position of the elevator
+	for {
+		select {
+		case a := <-c.drv_floors2:
+			lockMutexes(&c.mutex_posArray, &c.mutex_d)
+			// Even indices are floors, odd indices are in-between floors
+			// Get the current floor
+
+			currentFloor := 0
+			for i := 0; i < 2*numFloors-1; i++ {
+				if c.posArray[i] {
+					currentFloor = i
+				}
+			}
+
+			if a == -1 {
+
+				if c.d == elevio.MD_Up && currentFloor < 2*numFloors-2 {
+					c.posArray[currentFloor] = false
+					c.posArray[currentFloor+1] = true
+				}
+				if c.d == elevio.MD_Down && currentFloor > 0 {
+					c.posArray[currentFloor] = false
+					c.posArray[currentFloor-1] = true
+				}
+			} else {
+
+				c.posArray[currentFloor] = false
+				c.posArray[a*2] = true
+
+				// Set the floor indicator
+				c.driver.SetFloorIndicator(a)
+
+			}
+
+			unlockMutexes(&c.mutex_posArray, &c.mutex_d)
+		case new_dir := <-c.drv_DirectionChange:
+			lockMutexes(&c.mutex_posArray, &c.mutex_d)
+
+			currentFloor := 0
+			for i := 0; i < 2*numFloors-1; i++ {
+				if c.posArray[i] {
+					currentFloor = i
+				}
+			}
+
+			switch { // The car may already be past the floor (e.g. it was stopped between floors)
+			case new_dir == elevio.MD_Up && currentFloor < 2*numFloors-2:
+				c.posArray[currentFloor] = false
+				c.posArray[currentFloor+1] = true
+			case new_dir == elevio.MD_Down && currentFloor > 0:
+				c.posArray[currentFloor] = false
+				c.posArray[currentFloor-1] = true
+			case new_dir == elevio.MD_Stop:
+				// If the direction is alreadt MD_Stop we don't have to alter positionArray
+			}
+
+			unlockMutexes(&c.mutex_posArray, &c.mutex_d)
+		case <-c.ctx.Done():
+			return
+		}
+
+	}
+}
+
+func reverseDirection(d *elevio.MotorDirection) { // Reverse the direction of the elevator
+	switch {
+	case *d == elevio.MD_Down:
+		*d = elevio.MD_Up
+	case *d == elevio.MD_Up:
+		*d = elevio.MD_Down
+	case *d == elevio.MD_Stop:
+	}
+}
+
+// This function is only used internally in the sorting functions
+func updatePosArray(dir elevio.MotorDirection, posArray *[2*numFloors - 1]bool) {
+	// Reset all values in the array to false
+	for i := range posArray {
+		(posArray[i]) = false
+	}
+
+	switch {
+	case dir == elevio.MD_Down:
+		posArray[2*numFloors-2] = true
+	case dir == elevio.MD_Up:
+		posArray[0] = true
+	default:
+		panic("Error: MotorDirection MD_Stop passed into updatePosArray function")
+	}
+}
+
+func (c *Client) extractPos() float32 { // Extract the current position of the elevator
+	currentFloor := float32(0)
+	for i := 0; i < 2*numFloors-1; i++ {
+		if c.posArray[i] {
+			currentFloor = float32(i) / 2
+		}
+	}
+	return currentFloor
+}
+
+func (c *Client) addOrder(newOrder Order) { // Add an order to the elevatorOrders, unless we already have it (we keep the first one and its id)
+	exists := false
+
+	if newOrder.OrderType == cab {
+		for _, order := range c.elevatorOrders {
+			if order.Floor == newOrder.Floor && order.OrderType == cab {
+				exists = true
+			}
+		}
+	} else if newOrder.OrderType == hall {
+		for i, order := range c.elevatorOrders {
+			if order.SameButton(newOrder) { // Several groups can be picked up at the same floor, each one is kept
+				exists = true
+				if len(newOrder.Destinations) > len(order.Destinations) { // More passengers joined its group
+					c.elevatorOrders[i].Destinations = newOrder.Destinations
+				}
+			}
+		}
+	}
+
+	if !exists {
+		c.elevatorOrders = append(c.elevatorOrders, newOrder)
+	}
+}
+
+// This function deletes relevant orders at the same floor as the current order,
+// It takes into account if there are multiple orders to the same floor
+// Since elevatorOrders is sorted, we can just delete from left to right until there are no orders with the same floor left
+// Returns the deleted orders
+func (c *Client) popOrders() []Order {
+	var popped []Order
+	if len(c.elevatorOrders) != 0 {
+		floor_to_pop := c.elevatorOrders[0].Floor
+
+		// Figure out how many elements to delete
+		ndelete := 0
+		for _, order := range c.elevatorOrders {
+			if order.Floor == floor_to_pop {
+				ndelete += 1
+			} else {
+				break
+			}
+		}
+
+		// Remember the hall orders served while isolated, the master must know about them when we rejoin.
+		// There is no master to turn off their lights either, so we do it ourselves
+		c.mutex_isolated.Lock()
+		if c.isolated {
+			served := extractHallOrders(c.elevatorOrders[:ndelete])
+			c.servedOffline = append(c.servedOffline, served...)
+			c.turnOffHallLights(served...)
+		}
+		c.mutex_isolated.Unlock()
+
+		// Now that we've calculated the number of elements to delete, update elevatorOrders
+		popped = append(popped, c.elevatorOrders[:ndelete]...)
+		c.elevatorOrders = c.elevatorOrders[ndelete:]
+	}
+	return popped
+}
+
+// Tells the master when the orders were served, for its statistics (see statistics.go)
+func (c *Client) reportServedOrders(orders []Order, arrivedAt, doorClosedAt time.Time) {
+	if len(orders) == 0 {
+		return
+	}
+	for _, order := range orders {
+		c.logger(logFSM).Debugf("Served %s (%s)", order.Name(), order.Id)
+	}
+	go func() {
+		select {
+		case c.orderServedTx <- OrderServedMsg{Id: c.id, Orders: orders, ArrivedAt: arrivedAt, DoorClosedAt: doorClosedAt}:
+		case <-c.ctx.Done():
+		}
+	}()
+}
+
+func changeDirBasedOnCurrentOrder(d *elevio.MotorDirection, current_order Order, current_floor float32) { // Change the direction based on the current order
+	switch {
+	case current_floor > float32(current_order.Floor):
+		*d = elevio.MD_Down
+	case current_floor < float32(current_order.Floor):
+		*d = elevio.MD_Up
+	case current_floor == float32(current_order.Floor):
+		*d = elevio.MD_Stop
+	}
+}
+
+func (c *Client) stopBlocker(Inital_duration time.Duration) { // Block the elevator for a certain duration
+	Timer := Inital_duration
+	sleepDuration := 30 * time.Millisecond
+outerloop:
+	for {
+		switch {
+		case Timer <= time.Duration(0):
+			c.setDoorOpen(false)
+			break outerloop
+		case Timer > time.Duration(0):
+			switch {
+			case c.ableToCloseDoors:
+				Timer = Timer - sleepDuration
+			case !c.ableToCloseDoors:
+				Timer = Inital_duration
+
+			}
+		}
+		time.Sleep(sleepDuration)
+	}
+}
+
+func relayDrvFloors(source chan int, consumers ...chan int) {
+	for {
+		value := <-source
+		for _, consumer := range consumers {
+			consumer <- value // Send to each consumer
+		}
+	}
+}
+
+func relayDrvButtons(source chan elevio.ButtonEvent, consumers ...chan elevio.ButtonEvent) {
+	for {
+		value := <-source
+		for _, consumer := range consumers {
+			consumer <- value // Send to each consumer
+		}
+	}
+}
+
+func forwarderStateMsg(source chan StateMsg, consumers ...chan StateMsg) {
+	for value := range source {
+		for _, consumer := range consumers {
+			consumer <- value // Send to each consumer
+		}
+	}
+}
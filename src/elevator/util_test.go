@@ -0,0 +1,36 @@
+package elevator
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAddOrder(t *testing.T) {
+	// Returns the group name picked up at floor 1 going up, with the destinations of its passengers
+	groupAt := func(name string, destinations ...int) Order {
+		return Order{Floor: 1, Direction: up, OrderType: hall, Group: name, Destinations: destinations}
+	}
+	tests := []struct {
+		name   string
+		orders []Order
+		order  Order
+		want   []Order
+	}{
+		{"a new order", []Order{cabOrderAt(0)}, hallOrderAt(1, up), []Order{cabOrderAt(0), hallOrderAt(1, up)}},
+		{"the same hall button", []Order{hallOrderAt(1, up)}, hallOrderAt(1, up), []Order{hallOrderAt(1, up)}},
+		{"the same cab button", []Order{cabOrderAt(2)}, cabOrderAt(2), []Order{cabOrderAt(2)}},
+		{"the other direction", []Order{hallOrderAt(1, up)}, hallOrderAt(1, down), []Order{hallOrderAt(1, up), hallOrderAt(1, down)}},
+		{"more passengers join a group", []Order{groupAt("a", 3)}, groupAt("a", 3, 2), []Order{groupAt("a", 3, 2)}},
+		{"a group is not shrunk", []Order{groupAt("a", 3, 2)}, groupAt("a", 3), []Order{groupAt("a", 3, 2)}},
+		{"another group at the same floor", []Order{groupAt("a", 3, 2)}, groupAt("b", 2), []Order{groupAt("a", 3, 2), groupAt("b", 2)}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := &Client{elevatorOrders: append([]Order{}, test.orders...)}
+			c.addOrder(test.order)
+			if !reflect.DeepEqual(c.elevatorOrders, test.want) {
+				t.Errorf("elevatorOrders = %v, want %v", c.elevatorOrders, test.want)
+			}
+		})
+	}
+}
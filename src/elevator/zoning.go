@@ -0,0 +1,99 @@
+// This file contains the floors served by each car. A car can skip some floors (e.g. an express car, or a freight
+// car that is the only one to go down to the basement): it advertises the floors it serves in its state
+// (ElevState.ServedFloors), the master only gives it hall orders at these floors and parks it at one of them, and
+// its cab buttons of the other floors are refused with a blink of their lamp
+package elevator
+
+import (
+	"Driver-go/elevio"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parses Config.ServedFloors, e.g. "0,2-3". Returns nil for every floor
+func parseServedFloors(floors string) ([]int, error) {
+	if strings.TrimSpace(floors) == "" {
+		return nil, nil
+	}
+	served := make([]bool, numFloors)
+	for _, entry := range strings.Split(floors, ",") {
+		bounds := strings.SplitN(strings.TrimSpace(entry), "-", 2)
+		lowest, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+		if err != nil {
+			return nil, fmt.Errorf("The served floors entry %q must be <floor> or <lowest>-<highest>", entry)
+		}
+		highest := lowest
+		if len(bounds) == 2 {
+			if highest, err = strconv.Atoi(strings.TrimSpace(bounds[1])); err != nil {
+				return nil, fmt.Errorf("The served floors entry %q must be <floor> or <lowest>-<highest>", entry)
+			}
+		}
+		if lowest < 0 || highest >= numFloors || lowest > highest {
+			return nil, fmt.Errorf("The served floors entry %q is not a range of our floors", entry)
+		}
+		for floor := lowest; floor <= highest; floor++ {
+			served[floor] = true
+		}
+	}
+
+	list := []int{}
+	for floor, ok := range served {
+		if ok {
+			list = append(list, floor)
+		}
+	}
+	return list, nil
+}
+
+// Returns whether a car with this state stops at a floor. A car that did not tell (e.g. it is uninitialized)
+// serves all of them
+func servesFloor(state ElevState, floor int) bool {
+	if len(state.ServedFloors) == 0 {
+		return true
+	}
+	for _, served := range state.ServedFloors {
+		if served == floor {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Client) servesFloor(floor int) bool {
+	return servesFloor(ElevState{ServedFloors: c.servedFloors}, floor)
+}
+
+// Returns the candidates that serve a floor
+func servingElevators(allStates [numElev]ElevState, candidates []int, floor int) []int {
+	serving := []int{}
+	for _, id := range candidates {
+		if servesFloor(allStates[id], floor) {
+			serving = append(serving, id)
+		}
+	}
+	return serving
+}
+
+// Returns the floor a car serves that is the nearest to floor (floor itself if it serves it)
+func nearestServedFloor(state ElevState, floor int) int {
+	nearest := floor
+	for _, served := range state.ServedFloors {
+		if !servesFloor(state, nearest) || math.Abs(float64(served-floor)) < math.Abs(float64(nearest-floor)) {
+			nearest = served
+		}
+	}
+	return nearest
+}
+
+// Blinks the lamp of a button, to show that its call is refused. The lamp is left off
+func (c *Client) blinkLamp(button elevio.ButtonType, floor int) {
+	for i := 0; i < refusedCallBlinks; i++ {
+		c.driver.SetButtonLamp(button, floor, true)
+		time.Sleep(refusedCallBlinkRate)
+		c.driver.SetButtonLamp(button, floor, false)
+		time.Sleep(refusedCallBlinkRate)
+	}
+}
@@ -0,0 +1,89 @@
+package elevator
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseServedFloors(t *testing.T) {
+	tests := []struct {
+		floors  string
+		want    []int
+		wantErr bool
+	}{
+		{floors: "", want: nil},
+		{floors: "  ", want: nil},
+		{floors: "2", want: []int{2}},
+		{floors: "0,2-3", want: []int{0, 2, 3}},
+		{floors: " 3 , 1 - 2 ", want: []int{1, 2, 3}},
+		{floors: "1,1,0-2", want: []int{0, 1, 2}}, // Duplicates are served once
+		{floors: "0-3", want: []int{0, 1, 2, 3}},
+		{floors: "4", wantErr: true},
+		{floors: "-1", wantErr: true},
+		{floors: "2-5", wantErr: true},
+		{floors: "3-1", wantErr: true},
+		{floors: "0,", wantErr: true},
+		{floors: "a-b", wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.floors, func(t *testing.T) {
+			got, err := parseServedFloors(test.floors)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("parseServedFloors() error = %v, want error %v", err, test.wantErr)
+			}
+			if !test.wantErr && !reflect.DeepEqual(got, test.want) {
+				t.Errorf("parseServedFloors() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNearestServedFloor(t *testing.T) {
+	tests := []struct {
+		name   string
+		served []int
+		floor  int
+		want   int
+	}{
+		{"serves all the floors", nil, 1, 1},
+		{"serves the floor", []int{0, 1, 3}, 1, 1},
+		{"nearest above", []int{0, 3}, 2, 3},
+		{"nearest below", []int{0, 3}, 1, 0},
+		{"the lower one when both are as near", []int{0, 2}, 1, 0},
+		{"a floor out of the building", []int{0, 2}, numFloors, 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := nearestServedFloor(ElevState{ServedFloors: test.served}, test.floor); got != test.want {
+				t.Errorf("nearestServedFloor(%v, %d) = %d, want %d", test.served, test.floor, got, test.want)
+			}
+		})
+	}
+}
+
+func TestServingElevators(t *testing.T) {
+	var allStates [numElev]ElevState
+	allStates[0].ServedFloors = []int{0, 1, 2, 3}
+	allStates[1].ServedFloors = []int{1, 2, 3} // No basement
+	// Elevator 2 did not tell, it serves all of them
+
+	tests := []struct {
+		name       string
+		candidates []int
+		floor      int
+		want       []int
+	}{
+		{"no candidates", nil, 0, []int{}},
+		{"every candidate serves it", []int{0, 1, 2}, 2, []int{0, 1, 2}},
+		{"the basement", []int{0, 1, 2}, 0, []int{0, 2}},
+		{"only the candidates", []int{1}, 0, []int{}},
+		{"a floor out of the building", []int{0, 1, 2}, numFloors, []int{2}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := servingElevators(allStates, test.candidates, test.floor); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("servingElevators() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
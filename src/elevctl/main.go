@@ -0,0 +1,485 @@
+// elevctl inspects a running cluster of elevators. It listens to the broadcasts of the elevators without taking
+// part in the cluster (it does not announce itself as a peer), and can press hall buttons like a panel would
+// and drive a car in inspection
+//
+//	elevctl status                 The peers, their roles and the states of the cars
+//	elevctl watch                  The changes in the cluster, as they happen
+//	elevctl orders                 The hall calls and the queues of the cars, with the ids of the orders
+//	elevctl call --floor 2 --up    Press a hall button
+//	elevctl inspect --elevator 1 up  A command of the inspection mode: on, off, up, down or stop
+//	elevctl record traffic.jsonl   Record every datagram of the cluster (see record.go)
+//	elevctl replay traffic.jsonl   Print a recording, filtered, or the timeline of the roles (see replay.go)
+package main
+
+import (
+	"Driver-go/elevator"
+	"Network-go/network/peers"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"time"
+)
+
+const callAttempts = 3                    // The times a hall press is sent before giving up
+const callTimeout = 1 * time.Second       // How long we wait for the master to light a hall call
+const defaultListenTime = 1 * time.Second // How long status and orders listen before printing
+const watchRate = 500 * time.Millisecond  // The rate at which watch looks for changes in the states
+
+// The view of the cluster, built from what we hear on the network
+type clusterView struct {
+	peers    []peers.ElevIdentity
+	states   [elevator.NumElev]elevator.ElevState // As broadcast by the master
+	statesAt time.Time                            // Zero until a master was heard
+	lights   elevator.HallLightsMsg               // The hall calls of the master
+	lightsAt time.Time
+}
+
+// The channels on which the messages of the cluster are received
+type listener struct {
+	peerUpdates chan peers.PeerUpdate
+	states      chan [elevator.NumElev]elevator.ElevState
+	lights      chan elevator.HallLightsMsg
+	completed   chan []elevator.Order
+	served      chan elevator.OrderServedMsg
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	command, args := os.Args[1], os.Args[2:]
+	switch command {
+	case "status", "orders":
+		flags := flag.NewFlagSet(command, flag.ExitOnError)
+		listenTime := flags.Duration("listen", defaultListenTime, "How long to listen to the cluster before printing")
+		flags.Parse(args)
+
+		view := collect(ctx, listen(ctx), *listenTime)
+		if command == "status" {
+			printStatus(view)
+		} else {
+			printOrders(view)
+		}
+	case "watch":
+		flag.NewFlagSet(command, flag.ExitOnError).Parse(args)
+		watch(ctx, listen(ctx))
+	case "call":
+		flags := flag.NewFlagSet(command, flag.ExitOnError)
+		floor := flags.Int("floor", -1, "The floor of the hall button")
+		goingUp := flags.Bool("up", false, "Press the up button")
+		goingDown := flags.Bool("down", false, "Press the down button")
+		flags.Parse(args)
+
+		switch {
+		case *goingUp == *goingDown:
+			fmt.Println("Give either --up or --down")
+			os.Exit(2)
+		case *floor < 0 || *floor >= elevator.NumFloors,
+			*floor == elevator.NumFloors-1 && *goingUp,
+			*floor == 0 && *goingDown:
+			fmt.Println("There is no such button")
+			os.Exit(2)
+		}
+		if !call(ctx, listen(ctx), elevator.NewHallCall(*floor, *goingUp, fmt.Sprintf("elevctl-%d", os.Getpid()))) {
+			os.Exit(1)
+		}
+	case "inspect":
+		flags := flag.NewFlagSet(command, flag.ExitOnError)
+		elevatorId := flags.Int("elevator", -1, "The elevator to inspect")
+		flags.Parse(args)
+
+		switch {
+		case flags.NArg() != 1:
+			usage()
+		case *elevatorId < 0 || *elevatorId >= elevator.NumElev:
+			fmt.Println("There is no such elevator")
+			os.Exit(2)
+		}
+		switch flags.Arg(0) {
+		case "on", "off", "up", "down", "stop":
+		default:
+			fmt.Println("The command must be on, off, up, down or stop")
+			os.Exit(2)
+		}
+		if !inspect(ctx, *elevatorId, flags.Arg(0)) {
+			os.Exit(1)
+		}
+	case "record":
+		flags := flag.NewFlagSet(command, flag.ExitOnError)
+		maxSize := flags.Int64("max-size", 10, "Rotate the file once it reaches this size, in MB")
+		keep := flags.Int("keep", 5, "The number of rotated files kept")
+		flags.Parse(args)
+		if flags.NArg() != 1 {
+			usage()
+		}
+		if err := record(ctx, flags.Arg(0), *maxSize*1024*1024, *keep); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	case "replay":
+		flags := flag.NewFlagSet(command, flag.ExitOnError)
+		port := flags.String("port", "", "Only the datagrams of this port (number or name, e.g. PeerChannel)")
+		typeName := flags.String("type", "", "Only the messages whose type contains this (e.g. StateMsg)")
+		elevatorId := flags.Int("elevator", -1, "Only the messages from or about this elevator")
+		full := flags.Bool("full", false, "Print the whole messages")
+		showTimeline := flags.Bool("timeline", false, "Print the timeline of the roles (peers, master, shutdowns, rejoins, assignments)")
+		flags.Parse(args)
+		if flags.NArg() != 1 {
+			usage()
+		}
+
+		filter := replayFilter{typeName: *typeName, elevator: *elevatorId}
+		var err error
+		if filter.port, err = parsePort(*port); err == nil {
+			if *showTimeline {
+				err = replayTimeline(flags.Arg(0), filter)
+			} else {
+				err = replay(flags.Arg(0), filter, *full)
+			}
+		}
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Println("Usage: elevctl status [--listen 1s] | watch | orders [--listen 1s] | call --floor <floor> --up|--down")
+	fmt.Println("       elevctl inspect --elevator <id> on|off|up|down|stop")
+	fmt.Println("       elevctl record [--max-size 10] [--keep 5] <file>")
+	fmt.Println("       elevctl replay [--port <port>] [--type <type>] [--elevator <id>] [--full] [--timeline] <file>")
+	os.Exit(2)
+}
+
+// Starts listening to the cluster. The receivers stop with ctx
+func listen(ctx context.Context) *listener {
+	l := &listener{
+		peerUpdates: make(chan peers.PeerUpdate),
+		states:      make(chan [elevator.NumElev]elevator.ElevState),
+		lights:      make(chan elevator.HallLightsMsg),
+		completed:   make(chan []elevator.Order),
+		served:      make(chan elevator.OrderServedMsg),
+	}
+	transport := elevator.UDPTransport{}
+	go transport.PeerReceiver(ctx, elevator.PeerChannel_PORT, l.peerUpdates)
+	go transport.Receiver(ctx, elevator.SpamFromMaster_PORT, l.states)
+	go transport.Receiver(ctx, elevator.HallLights_PORT, l.lights) // The acknowledgements on this port are ignored
+	go transport.Receiver(ctx, elevator.HallOrderCompleted_PORT, l.completed)
+	go transport.Receiver(ctx, elevator.OrderServed_PORT, l.served)
+	return l
+}
+
+// Builds the view of the cluster from what is heard during listenTime
+func collect(ctx context.Context, l *listener, listenTime time.Duration) clusterView {
+	var view clusterView
+	deadline := time.After(listenTime)
+	for {
+		select {
+		case p := <-l.peerUpdates:
+			view.peers = p.Peers
+		case s := <-l.states:
+			view.states, view.statesAt = s, time.Now()
+		case h := <-l.lights:
+			view.lights, view.lightsAt = h, time.Now()
+		case <-l.completed:
+		case <-l.served:
+		case <-deadline:
+			return view
+		case <-ctx.Done():
+			return view
+		}
+	}
+}
+
+func printStatus(view clusterView) {
+	if len(view.peers) == 0 {
+		fmt.Println("No elevator heard on the network")
+	} else {
+		fmt.Println("Peers:")
+		for _, peer := range view.peers {
+			fmt.Printf("  %d  %s\n", peer.Id, peer.Role)
+		}
+	}
+
+	if view.statesAt.IsZero() {
+		fmt.Println("No master heard on the network")
+		return
+	}
+	fmt.Println("Cars (as broadcast by the master):")
+	fmt.Printf("  %-4s %-14s %-11s %-6s %-10s %-7s %s\n", "Car", "Behavior", "Mode", "Floor", "Direction", "Door", "Requests")
+	for id, state := range view.states {
+		if state.Behavior == "Uninitialized" || state.Behavior == "" {
+			continue
+		}
+		door := "closed"
+		if state.DoorOpen {
+			door = "open"
+		}
+		fmt.Printf("  %-4d %-14s %-11s %-6d %-10s %-7s %s\n", id, state.Behavior, state.Mode, state.Floor, state.Direction, door, orderNames(state.LocalRequests))
+	}
+	if len(view.lights.OutOfService) > 0 {
+		fmt.Printf("Out of service: %v\n", view.lights.OutOfService)
+	}
+	if view.lights.FireRecall {
+		fmt.Printf("Fire recall to floor %d\n", view.lights.RecallFloor)
+	}
+	if view.lights.TrafficMode != "" {
+		fmt.Printf("Traffic: %s (%s)\n", view.lights.TrafficMode, view.lights.TrafficSource)
+	}
+}
+
+func printOrders(view clusterView) {
+	if view.lightsAt.IsZero() {
+		fmt.Println("No master heard on the network")
+		return
+	}
+
+	fmt.Println("Hall calls:")
+	if len(view.lights.Orders)+len(view.lights.Pending) == 0 {
+		fmt.Println("  none")
+	}
+	for _, order := range sortedOrders(view.lights.Orders) {
+		status, car := "confirmed", ""
+		for id, state := range view.states {
+			for _, request := range state.LocalRequests {
+				if request.SameButton(order) {
+					status, car = "assigned", fmt.Sprintf(" to %d", id)
+				}
+			}
+		}
+		fmt.Printf("  %-8s %-9s%-5s %s%s\n", order.Name(), status, car, order.Id, orderAge(order))
+	}
+	for _, order := range sortedOrders(view.lights.Pending) {
+		fmt.Printf("  %-8s %-14s %s%s\n", order.Name(), "unconfirmed", order.Id, orderAge(order))
+	}
+
+	fmt.Println("Cab orders:")
+	found := false
+	for id, state := range view.states {
+		for _, order := range sortedOrders(state.LocalRequests) {
+			if strings.HasPrefix(order.Name(), "cab") {
+				found = true
+				fmt.Printf("  %-8s car %-10d %s%s\n", order.Name(), id, order.Id, orderAge(order))
+			}
+		}
+	}
+	if !found {
+		fmt.Println("  none")
+	}
+}
+
+// Prints the changes in the cluster until ctx is cancelled
+func watch(ctx context.Context, l *listener) {
+	var view clusterView
+	roles := make(map[int]string)
+	ticker := time.NewTicker(watchRate)
+	defer ticker.Stop()
+	var lastStates [elevator.NumElev]elevator.ElevState
+
+	for {
+		select {
+		case p := <-l.peerUpdates:
+			for _, peer := range p.Peers {
+				if role, known := roles[peer.Id]; !known {
+					printEvent("Elevator %d joined as %s", peer.Id, peer.Role)
+				} else if role != peer.Role {
+					printEvent("Elevator %d is now %s (was %s)", peer.Id, peer.Role, role)
+				}
+				roles[peer.Id] = peer.Role
+			}
+			for _, peer := range p.Lost {
+				printEvent("Elevator %d (%s) was lost", peer.Id, roles[peer.Id])
+				delete(roles, peer.Id)
+			}
+			view.peers = p.Peers
+
+		case s := <-l.states:
+			view.states = s
+
+		case h := <-l.lights:
+			for _, order := range h.Orders {
+				if !containsButton(view.lights.Orders, order) {
+					printEvent("Hall call %s is on (%s)", order.Name(), order.Id)
+				}
+			}
+			for _, order := range view.lights.Orders {
+				if !containsButton(h.Orders, order) {
+					printEvent("Hall call %s is off (%s)", order.Name(), order.Id)
+				}
+			}
+			view.lights = h
+
+		case orders := <-l.completed:
+			printEvent("Completed: %s", orderNames(orders))
+
+		case s := <-l.served:
+			for _, order := range s.Orders {
+				printEvent("Car %d served %s (%s) after %.1fs", s.Id, order.Name(), order.Id, s.ArrivedAt.Sub(order.CreatedAt).Seconds())
+			}
+
+		case <-ticker.C:
+			// The states are broadcast continuously, only their changes are printed
+			for id, state := range view.states {
+				last := lastStates[id]
+				if state.Behavior == "Uninitialized" || state.Behavior == "" {
+					continue
+				}
+				if state.Floor != last.Floor || state.Behavior != last.Behavior || state.Direction != last.Direction || state.DoorOpen != last.DoorOpen {
+					printEvent("Car %d: %s at floor %d, going %s, door %s", id, state.Behavior, state.Floor, state.Direction, doorName(state.DoorOpen))
+				}
+				if orderNames(state.LocalRequests) != orderNames(last.LocalRequests) {
+					printEvent("Car %d requests: %s", id, orderNames(state.LocalRequests))
+				}
+			}
+			lastStates = view.states
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Presses a hall button and waits for the master to light it. Returns false if no master answered
+func call(ctx context.Context, l *listener, order elevator.Order) bool {
+	presses := make(chan elevator.Order)
+	go elevator.UDPTransport{}.Transmitter(ctx, elevator.HallOrderRawBTN_PORT, presses)
+
+	for attempt := 0; attempt < callAttempts; attempt++ {
+		select {
+		case presses <- order: // The same order is sent again, the master knows it by its button
+		case <-ctx.Done():
+			return false
+		}
+
+		timeout := time.After(callTimeout)
+	waiting:
+		for {
+			select {
+			case h := <-l.lights:
+				for _, lit := range h.Orders {
+					if lit.SameButton(order) {
+						if lit.Id == order.Id {
+							fmt.Printf("Hall call %s is on (%s)\n", lit.Name(), lit.Id)
+						} else {
+							fmt.Printf("Hall call %s was already on (%s)\n", lit.Name(), lit.Id)
+						}
+						return true
+					}
+				}
+			case <-l.peerUpdates:
+			case <-l.states:
+			case <-l.completed:
+			case <-l.served:
+			case <-timeout:
+				break waiting
+			case <-ctx.Done():
+				return false
+			}
+		}
+	}
+	fmt.Printf("No master lit hall call %s\n", order.Name())
+	return false
+}
+
+// Sends a command of the inspection mode to an elevator until it answers. The command is sent again with the same
+// Seq, so that the elevator applies it once
+func inspect(ctx context.Context, id int, command string) bool {
+	transport := elevator.UDPTransport{}
+	commands := make(chan elevator.InspectionMsg)
+	acks := make(chan elevator.InspectionAckMsg)
+	go transport.Transmitter(ctx, elevator.Inspection_PORT, commands)
+	go transport.Receiver(ctx, elevator.Inspection_PORT, acks)
+
+	msg := elevator.InspectionMsg{Id: id, Command: command, Seq: fmt.Sprintf("elevctl-%d-%d", os.Getpid(), time.Now().UnixNano())}
+	for attempt := 0; attempt < callAttempts; attempt++ {
+		select {
+		case commands <- msg:
+		case <-ctx.Done():
+			return false
+		}
+
+		timeout := time.After(callTimeout)
+	waiting:
+		for {
+			select {
+			case a := <-acks:
+				if a.Seq != msg.Seq {
+					continue
+				}
+				if a.Error != "" {
+					fmt.Printf("Elevator %d refused %s: %s\n", id, command, a.Error)
+					return false
+				}
+				fmt.Printf("Elevator %d: %s (%s mode), floor %d, direction %s\n", id, a.State.Behavior, a.State.Mode, a.State.Floor, a.State.Direction)
+				return true
+			case <-timeout:
+				break waiting
+			case <-ctx.Done():
+				return false
+			}
+		}
+	}
+	fmt.Printf("Elevator %d did not answer\n", id)
+	return false
+}
+
+func printEvent(format string, args ...interface{}) {
+	fmt.Printf("%s  %s\n", time.Now().Format("15:04:05.000"), fmt.Sprintf(format, args...))
+}
+
+func orderNames(orders []elevator.Order) string {
+	if len(orders) == 0 {
+		return "-"
+	}
+	names := []string{}
+	for _, order := range orders {
+		names = append(names, order.Name())
+	}
+	return strings.Join(names, ", ")
+}
+
+func orderAge(order elevator.Order) string {
+	if order.CreatedAt.IsZero() {
+		return ""
+	}
+	return fmt.Sprintf(" (%.0fs ago)", time.Since(order.CreatedAt).Seconds())
+}
+
+func doorName(open bool) string {
+	if open {
+		return "open"
+	}
+	return "closed"
+}
+
+func containsButton(orders []elevator.Order, order elevator.Order) bool {
+	for _, o := range orders {
+		if o.SameButton(order) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the orders sorted by floor, then by button
+func sortedOrders(orders []elevator.Order) []elevator.Order {
+	sorted := append([]elevator.Order{}, orders...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Floor != sorted[j].Floor {
+			return sorted[i].Floor < sorted[j].Floor
+		}
+		return sorted[i].Name() < sorted[j].Name()
+	})
+	return sorted
+}
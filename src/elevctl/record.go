@@ -0,0 +1,204 @@
+// This file contains elevctl record: it captures every datagram broadcast on the ports of the cluster into a
+// rotating file, one JSON line per datagram (see replay.go for the viewer)
+package main
+
+import (
+	"Driver-go/elevator"
+	"Network-go/network/conn"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+const datagramSize = 16384 // The same as the buffer of bcast
+
+// A datagram captured by elevctl record
+type datagram struct {
+	Time     time.Time
+	Source   string // The address of the sender
+	Port     int
+	PortName string
+	Type     string          // The type of the message (e.g. elevator.StateMsg), "" if it could not be decoded
+	Elevator int             // The elevator the message is from or about, -1 if unknown
+	Payload  json.RawMessage // The message, or the datagram as a JSON string if it could not be decoded
+}
+
+// A file that is rotated once it reaches maxSize: path becomes path.1, path.1 becomes path.2, ... and only the
+// keep last ones are kept
+type rotatingFile struct {
+	path    string
+	maxSize int64
+	keep    int
+	file    *os.File
+	size    int64
+}
+
+func openRotatingFile(path string, maxSize int64, keep int) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, maxSize: maxSize, keep: keep}
+	return r, r.open()
+}
+
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file, r.size = file, info.Size()
+	return nil
+}
+
+func (r *rotatingFile) Write(line []byte) (int, error) {
+	if r.size > 0 && r.size+int64(len(line)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(line)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *rotatingFile) rotate() error {
+	r.file.Close()
+	os.Remove(fmt.Sprintf("%s.%d", r.path, r.keep)) // The oldest one goes
+	for i := r.keep - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+	}
+	if r.keep > 0 {
+		os.Rename(r.path, r.path+".1")
+	} else {
+		os.Remove(r.path)
+	}
+	return r.open()
+}
+
+func (r *rotatingFile) Close() error {
+	return r.file.Close()
+}
+
+// The files of a recording, the oldest first
+func recordingFiles(path string) []string {
+	rotated := []string{}
+	for i := 1; ; i++ {
+		name := fmt.Sprintf("%s.%d", path, i)
+		if _, err := os.Stat(name); err != nil {
+			break
+		}
+		rotated = append(rotated, name)
+	}
+	files := []string{}
+	for i := len(rotated) - 1; i >= 0; i-- {
+		files = append(files, rotated[i])
+	}
+	return append(files, path)
+}
+
+// Captures the datagrams of every port until ctx is cancelled
+func record(ctx context.Context, path string, maxSize int64, keep int) error {
+	out, err := openRotatingFile(path, maxSize, keep)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	ports := []int{}
+	for port := range elevator.PortNames {
+		ports = append(ports, port)
+	}
+	sort.Ints(ports)
+
+	datagrams := make(chan datagram)
+	for _, port := range ports {
+		go capturePort(ctx, port, datagrams)
+	}
+	fmt.Printf("Recording %d ports (%d to %d) to %s, Ctrl+C to stop\n", len(ports), ports[0], ports[len(ports)-1], path)
+
+	recorded := 0
+	for {
+		select {
+		case d := <-datagrams:
+			line, err := json.Marshal(d)
+			if err != nil {
+				continue
+			}
+			if _, err := out.Write(append(line, '\n')); err != nil {
+				return err
+			}
+			recorded++
+		case <-ctx.Done():
+			fmt.Printf("%d datagrams recorded\n", recorded)
+			return nil
+		}
+	}
+}
+
+func capturePort(ctx context.Context, port int, datagrams chan<- datagram) {
+	conn := conn.DialBroadcastUDP(port)
+	go func() {
+		// Unblocks ReadFrom
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	var buf [datagramSize]byte
+	for {
+		n, source, err := conn.ReadFrom(buf[0:])
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			continue
+		}
+		d := datagram{Time: time.Now(), Source: source.String(), Port: port, PortName: elevator.PortNames[port]}
+		d.Type, d.Payload = decodeDatagram(port, buf[:n])
+		d.Elevator = elevatorOf(d.Payload)
+
+		select {
+		case datagrams <- d:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Returns the type and the content of a datagram. The heartbeats of the peers are not type-tagged
+func decodeDatagram(port int, data []byte) (string, json.RawMessage) {
+	if port == elevator.PeerChannel_PORT && json.Valid(data) {
+		return "peers.ElevIdentity", append(json.RawMessage{}, data...)
+	}
+	var ttj struct { // The type-tagged JSON of bcast
+		TypeId string
+		JSON   []byte
+	}
+	if json.Unmarshal(data, &ttj) == nil && ttj.TypeId != "" && json.Valid(ttj.JSON) {
+		return ttj.TypeId, ttj.JSON
+	}
+	raw, _ := json.Marshal(string(data))
+	return "", raw
+}
+
+// Returns the elevator a message is from or about: its From field (e.g. the master in a HallLightsMsg), else its
+// Id (e.g. the sender of a StateMsg, the assignee of a HallOrderMsg), else the Origin of an order. -1 if none
+func elevatorOf(payload json.RawMessage) int {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(payload, &fields) != nil {
+		return -1
+	}
+	for _, name := range []string{"From", "Id", "ID", "Origin"} {
+		if value, ok := fields[name]; ok {
+			if id, err := strconv.Atoi(string(value)); err == nil {
+				return id
+			}
+		}
+	}
+	return -1
+}
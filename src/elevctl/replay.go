@@ -0,0 +1,195 @@
+// This file contains elevctl replay: the offline viewer of the recordings of elevctl record. It prints the
+// datagrams (filtered by port, type or elevator), or the timeline of the roles in the cluster (e.g. a failover)
+package main
+
+import (
+	"Driver-go/elevator"
+	"Network-go/network/peers"
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const peerTimeout = 500 * time.Millisecond // A peer is lost after this long without a heartbeat (the same as peers)
+const payloadShown = 120                   // The characters of a payload printed, without --full
+
+type replayFilter struct {
+	port     int    // -1 for every port
+	typeName string // A part of the type, "" for every type
+	elevator int    // -1 for every elevator
+}
+
+// Parses a port given as a number or as its name (e.g. PeerChannel)
+func parsePort(value string) (int, error) {
+	if value == "" {
+		return -1, nil
+	}
+	if port, err := strconv.Atoi(value); err == nil {
+		return port, nil
+	}
+	for port, name := range elevator.PortNames {
+		if strings.EqualFold(name, value) || strings.EqualFold(name+"_PORT", value) {
+			return port, nil
+		}
+	}
+	return 0, fmt.Errorf("Unknown port %s", value)
+}
+
+func (f replayFilter) match(d datagram) bool {
+	return (f.port == -1 || d.Port == f.port) &&
+		(f.typeName == "" || strings.Contains(strings.ToLower(d.Type), strings.ToLower(f.typeName))) &&
+		(f.elevator == -1 || d.Elevator == f.elevator)
+}
+
+// Reads the datagrams of a recording (with its rotated files), the oldest first
+func readRecording(path string, handle func(datagram)) error {
+	for _, name := range recordingFiles(path) {
+		file, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 4*datagramSize), 16*datagramSize) // The payloads are escaped
+		line := 0
+		for scanner.Scan() {
+			line++
+			var d datagram
+			if err := json.Unmarshal(scanner.Bytes(), &d); err != nil {
+				fmt.Printf("%s:%d: %v\n", name, line, err)
+				continue
+			}
+			handle(d)
+		}
+		file.Close()
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// Prints the datagrams of a recording that match the filter
+func replay(path string, filter replayFilter, full bool) error {
+	return readRecording(path, func(d datagram) {
+		if !filter.match(d) {
+			return
+		}
+		payload := string(d.Payload)
+		if !full && len(payload) > payloadShown {
+			payload = payload[:payloadShown] + "..."
+		}
+		elevatorName := "-"
+		if d.Elevator != -1 {
+			elevatorName = strconv.Itoa(d.Elevator)
+		}
+		fmt.Printf("%s  %-5d %-19s %-21s %-24s %-2s %s\n", d.Time.Format("15:04:05.000"), d.Port, d.PortName, d.Source, d.Type, elevatorName, payload)
+	})
+}
+
+// Rebuilds what happened to the roles of the cluster from a recording: the peers appearing, changing role and
+// being lost, the elevator broadcasting the hall lights (the master), the graceful shutdowns, the rejoins and the
+// hall orders assigned. The messages sent again and again are printed once
+type timeline struct {
+	filter     replayFilter
+	lastSeen   map[int]time.Time
+	roles      map[int]string
+	master     int
+	lastEvents map[string]string // The last event printed for each kind of message, to skip the repeated ones
+}
+
+func replayTimeline(path string, filter replayFilter) error {
+	t := &timeline{filter: filter, lastSeen: make(map[int]time.Time), roles: make(map[int]string), master: -1, lastEvents: make(map[string]string)}
+	return readRecording(path, t.handle)
+}
+
+func (t *timeline) print(at time.Time, elevatorId int, text string) {
+	if t.filter.elevator == -1 || t.filter.elevator == elevatorId {
+		fmt.Printf("%s  %s\n", at.Format("15:04:05.000"), text)
+	}
+}
+
+// Prints an event, unless it is the same as the last one of this kind
+func (t *timeline) printOnce(kind string, at time.Time, elevatorId int, text string) {
+	if t.lastEvents[kind] == text {
+		return
+	}
+	t.lastEvents[kind] = text
+	t.print(at, elevatorId, text)
+}
+
+// The peers not heard for peerTimeout before now are lost
+func (t *timeline) detectLostPeers(now time.Time) {
+	lost := []int{}
+	for id, seen := range t.lastSeen {
+		if now.Sub(seen) > peerTimeout {
+			lost = append(lost, id)
+		}
+	}
+	sort.Ints(lost)
+	for _, id := range lost {
+		t.print(t.lastSeen[id].Add(peerTimeout), id, fmt.Sprintf("Elevator %d (%s) is lost, last heard at %s", id, t.roles[id], t.lastSeen[id].Format("15:04:05.000")))
+		delete(t.lastSeen, id)
+		delete(t.roles, id)
+	}
+}
+
+func (t *timeline) handle(d datagram) {
+	t.detectLostPeers(d.Time)
+
+	switch d.Type {
+	case "peers.ElevIdentity":
+		var identity peers.ElevIdentity
+		if json.Unmarshal(d.Payload, &identity) != nil {
+			return
+		}
+		if _, alive := t.lastSeen[identity.Id]; !alive {
+			t.print(d.Time, identity.Id, fmt.Sprintf("Elevator %d is on the network (%s)", identity.Id, identity.Role))
+		} else if t.roles[identity.Id] != identity.Role {
+			t.print(d.Time, identity.Id, fmt.Sprintf("Elevator %d is now %s (was %s)", identity.Id, identity.Role, t.roles[identity.Id]))
+		}
+		t.lastSeen[identity.Id], t.roles[identity.Id] = d.Time, identity.Role
+
+	case "elevator.HallLightsMsg":
+		var msg elevator.HallLightsMsg
+		if json.Unmarshal(d.Payload, &msg) != nil || msg.From == t.master {
+			return
+		}
+		t.print(d.Time, msg.From, fmt.Sprintf("Elevator %d broadcasts the hall lights (it is the master)", msg.From))
+		t.master = msg.From
+
+	case "elevator.LeaveMsg":
+		var msg elevator.LeaveMsg
+		if json.Unmarshal(d.Payload, &msg) == nil {
+			t.printOnce(d.Type, d.Time, msg.Id, fmt.Sprintf("Elevator %d (%s) shuts down, %d hall orders to re-assign", msg.Id, msg.Role, len(msg.HallOrders)))
+		}
+
+	case "elevator.LeaveAckMsg":
+		var msg elevator.LeaveAckMsg
+		if json.Unmarshal(d.Payload, &msg) == nil {
+			t.printOnce(d.Type+strconv.Itoa(msg.From), d.Time, msg.From, fmt.Sprintf("Elevator %d acknowledges the shutdown of elevator %d", msg.From, msg.Id))
+		}
+
+	case "elevator.RejoinMsg":
+		var msg elevator.RejoinMsg
+		if json.Unmarshal(d.Payload, &msg) == nil {
+			t.printOnce(d.Type, d.Time, msg.Id, fmt.Sprintf("Elevator %d rejoins (it was %s while isolated, %d orders, %d served)", msg.Id, msg.Role, len(msg.Orders), len(msg.Served)))
+		}
+
+	case "elevator.RejoinAckMsg":
+		var msg elevator.RejoinAckMsg
+		if json.Unmarshal(d.Payload, &msg) == nil {
+			t.printOnce(d.Type, d.Time, msg.Id, fmt.Sprintf("Elevator %d (master) takes elevator %d back as %s", msg.From, msg.Id, msg.Role))
+		}
+
+	case "elevator.HallOrderMsg":
+		var msg elevator.HallOrderMsg
+		if json.Unmarshal(d.Payload, &msg) == nil {
+			t.printOnce(d.Type+msg.HallOrder.Name(), d.Time, msg.Id, fmt.Sprintf("Hall order %s (%s) is assigned to elevator %d", msg.HallOrder.Name(), msg.HallOrder.Id, msg.Id))
+		}
+	}
+}
@@ -1,18 +1,20 @@
 package elevio
 
 import (
-	"fmt"
+	"Network-go/network/logging"
 	"net"
 	"sync"
 	"time"
 )
 
 const _pollRate = 20 * time.Millisecond
+const _reconnectAttempts = 10                  // The number of times we try to reconnect to the server before giving up
+const _reconnectDelay = 200 * time.Millisecond // The time between two attempts
+
+var log = logging.New("driver")
 
 var _initialized bool = false
-var _numFloors int = 4
-var _mtx sync.Mutex
-var _conn net.Conn
+var _driver *Driver // The driver used by the package-level functions
 
 type MotorDirection int
 
@@ -25,61 +27,203 @@ const (
 type ButtonType int
 
 const (
-	BT_HallUp   ButtonType = 0
-	BT_HallDown            = 1
-	BT_Cab                 = 2
+	BT_HallUp      ButtonType = 0
+	BT_HallDown               = 1
+	BT_Cab                    = 2
+	BT_Destination            = 3 // A destination entered at the hall (destination dispatch), there is no such lamp
 )
 
 type ButtonEvent struct {
-	Floor  int
-	Button ButtonType
+	Floor       int
+	Button      ButtonType
+	Destination int // The floor entered, for BT_Destination only
+}
+
+// Driver is a connection to a single elevator server (hardware or simulator).
+// Several drivers can be used in the same process, one per elevator.
+type Driver struct {
+	numFloors    int
+	addr         string
+	mtx          sync.Mutex
+	conn         net.Conn
+	closed       bool
+	reconnectMtx sync.Mutex // Only one reconnect at a time, drv.mtx is not held while we wait for the server
+	reconnects   int
+}
+
+// Dial connects to the elevator server listening on addr
+func Dial(addr string, numFloors int) (*Driver, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return &Driver{numFloors: numFloors, addr: addr, conn: conn}, nil
+}
+
+// Reconnects returns the number of times the connection to the server was lost and opened again
+func (drv *Driver) Reconnects() int {
+	drv.mtx.Lock()
+	defer drv.mtx.Unlock()
+	return drv.reconnects
+}
+
+// Opens the connection to the server again (e.g. after a restart of the simulator) if broken is still the current
+// one. Panics if it is not back after a few attempts
+func (drv *Driver) reconnect(broken net.Conn) {
+	drv.reconnectMtx.Lock()
+	defer drv.reconnectMtx.Unlock()
+
+	drv.mtx.Lock()
+	current := drv.conn == broken && !drv.closed
+	drv.mtx.Unlock()
+	if !current { // Closed, or already opened again by another call
+		return
+	}
+	broken.Close()
+
+	for attempt := 0; attempt < _reconnectAttempts; attempt++ {
+		if conn, err := net.Dial("tcp", drv.addr); err == nil {
+			drv.mtx.Lock()
+			closed := drv.closed
+			if !closed {
+				drv.conn = conn
+				drv.reconnects++
+			}
+			drv.mtx.Unlock()
+
+			if closed {
+				conn.Close()
+			} else {
+				log.Warnf("Reconnected to the elevator server at %s", drv.addr)
+			}
+			return
+		}
+		if drv.isClosed() {
+			return
+		}
+		time.Sleep(_reconnectDelay)
+	}
+	panic("Lost connection to Elevator Server")
+}
+
+// Close closes the connection to the elevator server. The polling functions return after it.
+func (drv *Driver) Close() error {
+	drv.mtx.Lock()
+	defer drv.mtx.Unlock()
+	if drv.closed {
+		return nil
+	}
+	drv.closed = true
+	return drv.conn.Close()
+}
+
+func (drv *Driver) isClosed() bool {
+	drv.mtx.Lock()
+	defer drv.mtx.Unlock()
+	return drv.closed
 }
 
 func Init(addr string, numFloors int) {
 	if _initialized {
-		fmt.Println("Driver already initialized!")
+		log.Warnf("Driver already initialized!")
 		return
 	}
-	_numFloors = numFloors
-	_mtx = sync.Mutex{}
 	var err error
-	_conn, err = net.Dial("tcp", addr)
+	_driver, err = Dial(addr, numFloors)
 	if err != nil {
 		panic(err.Error())
 	}
 	_initialized = true
 }
 
+// The package-level functions below operate on the driver set up by Init
+
 func SetMotorDirection(dir MotorDirection) {
-	write([4]byte{1, byte(dir), 0, 0})
+	_driver.SetMotorDirection(dir)
 }
 
 func SetButtonLamp(button ButtonType, floor int, value bool) {
-	write([4]byte{2, byte(button), byte(floor), toByte(value)})
+	_driver.SetButtonLamp(button, floor, value)
 }
 
 func SetFloorIndicator(floor int) {
-	write([4]byte{3, byte(floor), 0, 0})
+	_driver.SetFloorIndicator(floor)
 }
 
 func SetDoorOpenLamp(value bool) {
-	write([4]byte{4, toByte(value), 0, 0})
+	_driver.SetDoorOpenLamp(value)
 }
 
 func SetStopLamp(value bool) {
-	write([4]byte{5, toByte(value), 0, 0})
+	_driver.SetStopLamp(value)
 }
 
-// Updates receiver whenever a new button is pressed
 func PollButtons(receiver chan<- ButtonEvent) {
-	prev := make([][3]bool, _numFloors)
-	for {
+	_driver.PollButtons(receiver)
+}
+
+func PollFloorSensor(receiver chan<- int) {
+	_driver.PollFloorSensor(receiver)
+}
+
+func PollFloorSensor2(receiver chan<- int) {
+	_driver.PollFloorSensor2(receiver)
+}
+
+func PollStopButton(receiver chan<- bool) {
+	_driver.PollStopButton(receiver)
+}
+
+func PollObstructionSwitch(receiver chan<- bool) {
+	_driver.PollObstructionSwitch(receiver)
+}
+
+func GetButton(button ButtonType, floor int) bool {
+	return _driver.GetButton(button, floor)
+}
+
+func GetFloor() int {
+	return _driver.GetFloor()
+}
+
+func GetStop() bool {
+	return _driver.GetStop()
+}
+
+func GetObstruction() bool {
+	return _driver.GetObstruction()
+}
+
+func (drv *Driver) SetMotorDirection(dir MotorDirection) {
+	drv.write([4]byte{1, byte(dir), 0, 0})
+}
+
+func (drv *Driver) SetButtonLamp(button ButtonType, floor int, value bool) {
+	drv.write([4]byte{2, byte(button), byte(floor), toByte(value)})
+}
+
+func (drv *Driver) SetFloorIndicator(floor int) {
+	drv.write([4]byte{3, byte(floor), 0, 0})
+}
+
+func (drv *Driver) SetDoorOpenLamp(value bool) {
+	drv.write([4]byte{4, toByte(value), 0, 0})
+}
+
+func (drv *Driver) SetStopLamp(value bool) {
+	drv.write([4]byte{5, toByte(value), 0, 0})
+}
+
+// Updates receiver whenever a new button is pressed
+func (drv *Driver) PollButtons(receiver chan<- ButtonEvent) {
+	prev := make([][3]bool, drv.numFloors)
+	for !drv.isClosed() {
 		time.Sleep(_pollRate)
-		for f := 0; f < _numFloors; f++ {
+		for f := 0; f < drv.numFloors; f++ {
 			for b := ButtonType(0); b < 3; b++ {
-				v := GetButton(b, f)
+				v := drv.GetButton(b, f)
 				if v != prev[f][b] && v != false {
-					receiver <- ButtonEvent{f, ButtonType(b)}
+					receiver <- ButtonEvent{Floor: f, Button: ButtonType(b)}
 				}
 				prev[f][b] = v
 			}
@@ -88,11 +232,11 @@ func PollButtons(receiver chan<- ButtonEvent) {
 }
 
 // Updates the currnent floor of the elevator
-func PollFloorSensor(receiver chan<- int) {
+func (drv *Driver) PollFloorSensor(receiver chan<- int) {
 	prev := -1
-	for {
+	for !drv.isClosed() {
 		time.Sleep(_pollRate)
-		v := GetFloor()
+		v := drv.GetFloor()
 		if v != prev && v != -1 {
 			receiver <- v
 		}
@@ -101,11 +245,11 @@ func PollFloorSensor(receiver chan<- int) {
 }
 
 // Updates the currnent floor of the elevator
-func PollFloorSensor2(receiver chan<- int) {
+func (drv *Driver) PollFloorSensor2(receiver chan<- int) {
 	prev := -1
-	for {
+	for !drv.isClosed() {
 		time.Sleep(_pollRate)
-		v := GetFloor()
+		v := drv.GetFloor()
 		if v != prev {
 			receiver <- v
 		}
@@ -113,11 +257,11 @@ func PollFloorSensor2(receiver chan<- int) {
 	}
 }
 
-func PollStopButton(receiver chan<- bool) {
+func (drv *Driver) PollStopButton(receiver chan<- bool) {
 	prev := false
-	for {
+	for !drv.isClosed() {
 		time.Sleep(_pollRate)
-		v := GetStop()
+		v := drv.GetStop()
 		if v != prev {
 			receiver <- v
 		}
@@ -126,11 +270,11 @@ func PollStopButton(receiver chan<- bool) {
 }
 
 // Updates the current obstruction state
-func PollObstructionSwitch(receiver chan<- bool) {
+func (drv *Driver) PollObstructionSwitch(receiver chan<- bool) {
 	prev := false
-	for {
+	for !drv.isClosed() {
 		time.Sleep(_pollRate)
-		v := GetObstruction()
+		v := drv.GetObstruction()
 		if v != prev {
 			receiver <- v
 		}
@@ -139,14 +283,14 @@ func PollObstructionSwitch(receiver chan<- bool) {
 }
 
 // Returns true if the button is pressed, false else
-func GetButton(button ButtonType, floor int) bool {
-	a := read([4]byte{6, byte(button), byte(floor), 0})
+func (drv *Driver) GetButton(button ButtonType, floor int) bool {
+	a := drv.read([4]byte{6, byte(button), byte(floor), 0})
 	return toBool(a[1])
 }
 
 // Returns the nb of the floor at which we are (or -1 if we're between two floors)
-func GetFloor() int {
-	a := read([4]byte{7, 0, 0, 0})
+func (drv *Driver) GetFloor() int {
+	a := drv.read([4]byte{7, 0, 0, 0})
 	if a[1] != 0 {
 		return int(a[2])
 	} else {
@@ -154,43 +298,53 @@ func GetFloor() int {
 	}
 }
 
-func GetStop() bool {
-	a := read([4]byte{8, 0, 0, 0})
+func (drv *Driver) GetStop() bool {
+	a := drv.read([4]byte{8, 0, 0, 0})
 	return toBool(a[1])
 }
 
 // Returns true if the elevator is obstructed
-func GetObstruction() bool {
-	a := read([4]byte{9, 0, 0, 0})
+func (drv *Driver) GetObstruction() bool {
+	a := drv.read([4]byte{9, 0, 0, 0})
 	return toBool(a[1])
 }
 
-func read(in [4]byte) [4]byte {
-	_mtx.Lock()
-	defer _mtx.Unlock()
-
-	_, err := _conn.Write(in[:])
-	if err != nil {
-		panic("Lost connection to Elevator Server")
+func (drv *Driver) read(in [4]byte) [4]byte {
+	out, conn, err := drv.exchange(in, true)
+	if err != nil { // Try again on a new connection
+		drv.reconnect(conn)
+		if out, _, err = drv.exchange(in, true); err != nil {
+			panic("Lost connection to Elevator Server")
+		}
 	}
+	return out
+}
 
-	var out [4]byte
-	_, err = _conn.Read(out[:])
-	if err != nil {
-		panic("Lost connection to Elevator Server")
+func (drv *Driver) write(in [4]byte) {
+	_, conn, err := drv.exchange(in, false)
+	if err != nil { // Try again on a new connection
+		drv.reconnect(conn)
+		if _, _, err = drv.exchange(in, false); err != nil {
+			panic("Lost connection to Elevator Server")
+		}
 	}
-
-	return out
 }
 
-func write(in [4]byte) {
-	_mtx.Lock()
-	defer _mtx.Unlock()
+// Sends a command to the server, and reads its answer if reply. Returns the connection used
+func (drv *Driver) exchange(in [4]byte, reply bool) ([4]byte, net.Conn, error) {
+	drv.mtx.Lock()
+	defer drv.mtx.Unlock()
 
-	_, err := _conn.Write(in[:])
-	if err != nil {
-		panic("Lost connection to Elevator Server")
+	var out [4]byte
+	if drv.closed {
+		return out, drv.conn, nil
+	}
+
+	_, err := drv.conn.Write(in[:])
+	if err == nil && reply {
+		_, err = drv.conn.Read(out[:])
 	}
+	return out, drv.conn, err
 }
 
 func toByte(a bool) byte {
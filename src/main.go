@@ -1,192 +1,143 @@
 package main
 
 import (
+	"Driver-go/elevator"
 	"Driver-go/elevio"
-	"Network-go/network/bcast"
-	"Network-go/network/peers"
+	"Network-go/network/logging"
 	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 )
 
+const shutdownTimeout = 30 * time.Second // The time we leave to the elevator for reaching a floor and handing over its role
+
+var log = logging.New("client") // Same component as the start and shutdown logs of the client
+
 func main() {
 	// Section_START -- FLAGS & ROLE
-	port, initialRole, id := getFlags()
-	roleChannel := make(chan string)
+	port, cfg, statsPath := getFlags()
 	// Section_END -- FLAGS
 
-	currentRole := initialRole
+	// Initialize the elevator
+	driver, err := elevio.Dial("localhost:"+port, elevator.NumFloors)
+	if err != nil {
+		panic(err.Error())
+	}
 
-	// Section_START -- NETWORK INITIALIZATION
-	peerUpdateCh := make(chan peers.PeerUpdate)                           // Updates from peers
-	peerTxEnable := make(chan bool)                                       // Enables/disables the transmitter
-	go peers.Transmitter(PeerChannel_PORT, id, roleChannel, peerTxEnable) // Broadcast role
-	roleChannel <- currentRole
-	go peers.Receiver(PeerChannel_PORT, peerUpdateCh) // Listen for updates
+	client, err := elevator.New(cfg, driver, elevator.NewUDPTransport())
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	logging.SetIdentity(func() (int, string) { return client.Id(), client.Role() }) // For the logs of the network and the driver
+
+	// Section_START -- GRACEFUL SHUTDOWN
+	// On SIGINT/SIGTERM, the elevator hands over its orders and its role before exiting
+	// A second signal kills the client instantly
+	signalCtx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	shutdownDone := make(chan bool)
+	go func() {
+		defer close(shutdownDone)
+		<-signalCtx.Done()
+		stopSignals()
+
+		log.Infof("Shutting down...")
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := client.Shutdown(ctx); err != nil {
+			log.Warnf("Shutdown was not acknowledged: %v", err)
+		}
+	}()
+	// Section_END -- GRACEFUL SHUTDOWN
+
+	// Section_START -- STATISTICS
+	// On SIGUSR1, the wait and journey time statistics are written to <stats>.csv and <stats>.json
+	statsSignal := make(chan os.Signal, 1)
+	signal.Notify(statsSignal, syscall.SIGUSR1)
+	go func() {
+		for range statsSignal {
+			writeStatistics(client, statsPath)
+		}
+	}()
+	// Section_END -- STATISTICS
+
+	client.Run(context.Background()) // Returns once the client is stopped by Shutdown
+	<-shutdownDone
+	driver.Close()
+
+	log.Infof("Hall orders re-assigned by the watchdog: %d", client.HallOrderReassignments())
+	for _, line := range strings.Split(strings.TrimRight(client.OrderStatistics().Summary(), "\n"), "\n") {
+		log.Infof("%s", line)
+	}
+	if statsPath != "" {
+		writeStatistics(client, statsPath)
+	}
+}
 
-	// Check if the ID of the elevator is valid
+// Writes the order statistics of the client to path.csv and path.json
+func writeStatistics(client *elevator.Client, path string) {
+	if path == "" {
+		log.Warnf("No --stats path was given, the statistics are not written")
+		return
+	}
 
-	// Section_END -- NETWORK INITIALIZATION
+	stats := client.OrderStatistics()
+	for extension, write := range map[string]func(io.Writer) error{".csv": stats.WriteCSV, ".json": stats.WriteJSON} {
+		file, err := os.Create(path + extension)
+		if err == nil {
+			err = write(file)
+			file.Close()
+		}
+		if err != nil {
+			log.Errorf("Could not write the statistics to %s%s: %v", path, extension, err)
+			return
+		}
+	}
+	log.Infof("Statistics written to %s.csv and %s.json", path, path)
+}
 
-	// Section_START -- CHANNELS
-	// Initialize the elevator
-	elevio.Init("localhost:"+port, numFloors)
-
-	// Channels for the driver
-	drv_buttons := make(chan elevio.ButtonEvent, 100)
-	drv_floors := make(chan int)
-	drv_floors2 := make(chan int)
-	drv_obstr := make(chan bool)
-	drv_stop := make(chan bool)
-	drv_newOrder := make(chan Order)
-	drv_DirectionChange := make(chan elevio.MotorDirection)
-	localStatesForCabOrders := make(chan StateMsg) // ALL - Turn off cab lights after completing order
-	selfUpdate := make(chan StateMsg)              // ALL - Check for updates of the state to prevent loosing the elevator
-
-	drv_buttons_forCabLights := make(chan elevio.ButtonEvent, 100)
-	drv_buttons_forOrderHandling := make(chan elevio.ButtonEvent, 100)
-	go relayDrvButtons(drv_buttons, drv_buttons_forCabLights, drv_buttons_forOrderHandling)
-
-	go elevio.PollButtons(drv_buttons)         // Button updates
-	go elevio.PollFloorSensor(drv_floors)      // Floors updates
-	go elevio.PollFloorSensor2(drv_floors2)    // Floors updates (for tracking position)
-	go elevio.PollObstructionSwitch(drv_obstr) // Obstruction updates
-	go elevio.PollStopButton(drv_stop)         // Stop button presses
-
-	// Channels for the network
-	hallBtnTx := make(chan elevio.ButtonEvent)       // ALL - Send hall orders to the master
-	hallOrderRx := make(chan HallOrderMsg)           // ALL - Receive hall orders from the master
-	singleStateTx := make(chan StateMsg)             // ALL - Send the state of the elevator to the master
-	hallOrderCompletedLightsRx := make(chan []Order) // ALL - Confirm hall order (for lights)
-	activeElevatorsChannelTx := make(chan []int)     // ALL - The channel on which we send the active elevators list
-	activeElevatorsChannelRx := make(chan []int)     // ALL - The channel on which we receive the active elevators list
-	retrieveCabOrdersRx := make(chan CabOrderMsg)    // ALL - Retrieve the cab orders from the master
-	askForCabOrdersTx := make(chan int)              // ALL - Ask for the cab orders from the master
-
-	allStatesFromMasterRx := make(chan [numElev]ElevState) // ALL - Receive all states from the master
-	singleStateFromSlaveTx := make(chan StateMsg)          // ALL - Send the state of the elevator to the master
-
-	go bcast.Receiver(HallOrder_PORT, hallOrderRx)
-	go bcast.Transmitter(HallOrderRawBTN_PORT, hallBtnTx)
-	go bcast.Transmitter(SingleElevatorState_PORT, singleStateTx)
-	go bcast.Receiver(HallOrderCompleted_PORT, hallOrderCompletedLightsRx)
-	go bcast.Receiver(ActiveElevators_PORT, activeElevatorsChannelRx)
-	go bcast.Transmitter(ActiveElevators_PORT, activeElevatorsChannelTx)
-	go bcast.Receiver(RetrieveCabOrders_PORT, retrieveCabOrdersRx)
-	go bcast.Transmitter(AskForCabOrders_PORT, askForCabOrdersTx)
-	go bcast.Receiver(SpamFromMaster_PORT, allStatesFromMasterRx)
-	go bcast.Transmitter(SpamFromSlave_PORT, singleStateFromSlaveTx)
-
-	go forwarderStateMsg(singleStateTx, selfUpdate)
-
-	// Channels for specific roles
-	hallBtnRx := make(chan elevio.ButtonEvent)      // MASTER - Receive hall orders from slaves
-	hallOrderTx := make(chan HallOrderMsg)          // MASTER - Send hall orders to slaves
-	singleStateRx := make(chan StateMsg)            // MASTER - Receive states from slaves
-	backupStatesRx := make(chan [numElev]ElevState) // BACKUP - Receive all states from master
-	backupStatesTx := make(chan [numElev]ElevState) // MASTER - Send all states to backup
-	newStatesRx := make(chan [numElev]ElevState)    // MASTER - Receive all NEW states from backup
-	newStatesTx := make(chan [numElev]ElevState)    // BACKUP - Send all states to the NEW master
-	hallOrderCompletedTx := make(chan []Order)      // Master - Send completed hallorder(s) to single elevators
-	retrieveCabOrdersTx := make(chan CabOrderMsg)   // ALL - Retrieve the cab orders from the master
-	askForCabOrdersRx := make(chan int)             // ALL - Ask for the cab orders from the master
-
-	allStatesFromMasterTx := make(chan [numElev]ElevState) // ALL - Send all states to the master
-	singleStateFromSlaveRx := make(chan StateMsg)          // ALL - Receive the state of the elevator from the master
-
-	go bcast.Transmitter(BackupStates_PORT, newStatesTx) // LOCAL - Used to send the states to the NEW master (used in role changes)
-
-	_ = hallBtnRx
-	_ = hallOrderTx
-	_ = singleStateRx
-	_ = backupStatesTx
-	_ = newStatesRx
-	_ = retrieveCabOrdersTx
-	_ = askForCabOrdersRx
-	_ = allStatesFromMasterTx
-	_ = singleStateFromSlaveRx
-
-	// Section_END -- CHANNELS
-
-	// Create a context for stopping unwanted master routines
-	ctx, cancel := context.WithCancel(context.Background())
-	_ = ctx
-	_ = cancel
-
-	askForCabOrdersTx <- id // Ask for the cab orders from the master
-
-	// Section_START -- ROLES-SPECIFIC ACTIONS
-	switch role {
-	case "Master":
-
-		activeElevators = append(activeElevators, id) // Add the master to the activeElevators list
-
-		// Starting the Master Routine
-		go MasterRoutine(hallBtnRx, singleStateRx, hallOrderTx, backupStatesTx, newStatesRx, hallOrderCompletedTx,
-			retrieveCabOrdersTx, askForCabOrdersRx, ctx, hallBtnTx, activeElevatorsChannelTx, allStatesFromMasterTx,
-			singleStateFromSlaveRx)
-
-		// This is the initial states of the elevators
-		var allStates [numElev]ElevState
-		allStates = initAllStates(allStates)
-
-		// Send the initial states to the master
-		newStatesRx <- allStates
-
-	case "PrimaryBackup":
-
-		// Starting the PrimaryBackup Routine
-		go PrimaryBackupRoutine(backupStatesRx)
+func getFlags() (string, elevator.Config, string) {
+	// Decide the port on which we are working (for the server) & the role of the elevator
+	port_raw := flag.String("port", "", "The port of the elevator client / server")
+	role_raw := flag.String("role", "", "The role of the elevator")
+	id_raw := flag.Int("id", -1, "The id of the elevator")
+	timeoutFactor := flag.Float64("hall-timeout-factor", 0, "Re-assign a hall order after this multiple of its estimated service time (default 3)")
+	metricsAddr := flag.String("metrics", "", "Serve the Prometheus metrics on this address (e.g. :9100)")
+	dashboardAddr := flag.String("dashboard", "", "Serve the live dashboard on this address (e.g. :8080)")
+	apiAddr := flag.String("api", "", "Serve the control API on this address (e.g. :8081)")
+	logLevel := flag.String("log-level", "info", "The level of the logs: debug, info, warn or error")
+	logComponents := flag.String("log", "", "The levels of some components of the logs (e.g. bcast=debug,peers=warn)")
+	logJSON := flag.Bool("log-json", false, "Write the logs as JSON, one object per line")
+	fireRecallFloor := flag.Int("fire-recall-floor", 0, "The floor the cars are sent to by the fire recall")
+	lobbyFloor := flag.Int("lobby-floor", 0, "The floor where the up-peak starts and the down-peak ends")
+	trafficSchedule := flag.String("traffic-schedule", "", "The traffic modes by time of day (e.g. 07:30-09:30=upPeak,16:30-18:00=downPeak)")
+	parkingPolicy := flag.String("parking", "none", "Where the idle cars wait in normal traffic: none, lobby, zones or demand")
+	parkingDelay := flag.Duration("parking-delay", 0, "How long a car stays idle before it is parked (default 20s)")
+	destinationDispatch := flag.Bool("destination-dispatch", false, "The passengers enter their destination at the hall instead of pressing up or down")
+	servedFloors := flag.String("served-floors", "", "The floors this car stops at, e.g. 0,2-3 (all of them by default)")
+	statsPath := flag.String("stats", "", "Write the order statistics to <stats>.csv and <stats>.json on SIGUSR1 and on exit")
+	flag.Parse()
+
+	port := *port_raw
+
+	// If the port is not a number, cancel the program
+	if port == "" {
+		fmt.Println("Port must be a number")
+		os.Exit(1)
+	}
 
+	components, err := logging.ParseComponents(*logComponents)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
 	}
-	// Section_END -- ROLES-SPECIFIC ACTIONS
-
-	// Section_START -- LOCAL INITIALIZATION
-	var d elevio.MotorDirection = elevio.MD_Down // Setting the initial direction of the elevator
-
-	// Initialize the elevator - going to ground floor
-	initSingleElev(d, drv_floors)
-
-	consumer1drv_floors := make(chan int) // Consumers for the drv_floors (relay)
-	consumer2drv_floors := make(chan int)
-	consumer3drv_floors := make(chan int)
-	go relayDrvFloors(drv_floors, consumer1drv_floors, consumer2drv_floors, consumer3drv_floors)
-
-	d = elevio.MD_Stop // Update d so that states are accurate
-
-	// Send the initial state of the elevator to the master
-	singleStateTx <- StateMsg{id, latestState}
-
-	// Starting the goroutines for tracking the position of the elevator & attending to specific orders
-	go trackPosition(drv_floors2, drv_DirectionChange, &d) // Starts tracking the position of the elevator
-	go attendToSpecificOrder(&d, consumer2drv_floors, drv_newOrder, drv_DirectionChange, singleStateTx, id, localStatesForCabOrders)
-
-	// Section_START -- RERTIEVE CAB ORDERS
-	// We send our ID to the master to ask for the cab orders
-	askForCabOrdersTx <- id
-	// Secton_END -- RETRIEVE CAB ORDERS
-
-	updateState(&d, lastFloor, elevatorOrders, &latestState)
-	singleStateTx <- StateMsg{id, latestState}
-
-	// Section_END -- LOCAL INITIALIZATION
-
-	go handleFloorLights(consumer3drv_floors)
-	go handleObstruction(drv_obstr)                                                                    // Listens to the obstruction button
-	go handleElevatorUpdate(activeElevatorsChannelRx)                                                  // Listens to active elevators updates
-	go handleButtonPress(drv_buttons_forOrderHandling, hallBtnTx, &d, singleStateTx, id, drv_newOrder) // Listens to new button presses
-	go handleNewFloorReached(consumer1drv_floors, &d, singleStateTx, id)                               // Listens to floor updates
-	go handleNewHallOrder(hallOrderRx, id, &d, singleStateTx, drv_newOrder, hallOrderCompletedTx)      // Listens to new orders from the master
-	go handlePeerUpdate(peerUpdateCh, currentRole, activeElevatorsChannelTx, backupStatesRx,
-		hallBtnRx, singleStateRx, hallOrderTx, backupStatesTx, newStatesRx, hallOrderCompletedTx,
-		retrieveCabOrdersTx, askForCabOrdersRx, newStatesTx, roleChannel, hallBtnTx, id, ctx, cancel,
-		allStatesFromMasterTx, singleStateFromSlaveRx) // Listens to peer updates on the network
-	go handleTurnOffLightsHallOrderCompleted(hallOrderCompletedLightsRx) // Listens for completed hall orders
-	go handleTurnOffLightsCabOrderCompleted(localStatesForCabOrders)
-	go handleTurnOnLightsCabOrder(drv_buttons_forCabLights)
-	go handleRetrieveCab(retrieveCabOrdersRx, id, &d, singleStateTx, drv_newOrder) // Listens for cab order retrieving
-	go handleStopButton(drv_stop, &d, id, activeElevatorsChannelTx, hallBtnTx)     // Listens for stop button presses
-
-	go receiveSpamFromMaster(allStatesFromMasterRx, id)
-	go spamMaster(singleStateFromSlaveTx, id) // Sends the state of the elevator to the master periodically
-
-	select {}
+	logConfig := logging.Config{Level: *logLevel, Components: components, JSON: *logJSON}
+
+	return port, elevator.Config{Id: *id_raw, Role: *role_raw, HallOrderTimeoutFactor: *timeoutFactor, MetricsAddr: *metricsAddr, DashboardAddr: *dashboardAddr, APIAddr: *apiAddr, FireRecallFloor: *fireRecallFloor, LobbyFloor: *lobbyFloor, TrafficSchedule: *trafficSchedule, ParkingPolicy: *parkingPolicy, ParkingDelay: *parkingDelay, ServedFloors: *servedFloors, DestinationDispatch: *destinationDispatch, Log: logConfig}, *statsPath
 }
@@ -4,6 +4,7 @@ import (
 	"Network-go/network/bcast"
 	"Network-go/network/localip"
 	"Network-go/network/peers"
+	"context"
 	"flag"
 	"fmt"
 	"os"
@@ -44,8 +45,12 @@ func main() {
 	// We can disable/enable the transmitter after it has been started.
 	// This could be used to signal that we are somehow "unavailable".
 	peerTxEnable := make(chan bool)
-	go peers.Transmitter(15647, peers.ElevIdentity{}, peerTxEnable)
-	go peers.Receiver(15647, peerUpdateCh)
+	// The role is sent along with our id. Cancelling the context stops the
+	//  transmitters and receivers and closes their sockets.
+	ctx := context.Background()
+	roleCh := make(chan string)
+	go peers.Transmitter(ctx, 15647, os.Getpid(), roleCh, peerTxEnable)
+	go peers.Receiver(ctx, 15647, peerUpdateCh)
 
 	// We make channels for sending and receiving our custom data types
 	helloTx := make(chan HelloMsg)
@@ -53,8 +58,8 @@ func main() {
 	// ... and start the transmitter/receiver pair on some port
 	// These functions can take any number of channels! It is also possible to
 	//  start multiple transmitters/receivers on the same port.
-	go bcast.Transmitter(16569, helloTx)
-	go bcast.Receiver(16569, helloRx)
+	go bcast.Transmitter(ctx, 16569, helloTx)
+	go bcast.Receiver(ctx, 16569, helloRx)
 
 	// The example message. We just send one of these every second.
 	go func() {
@@ -2,20 +2,75 @@ package bcast
 
 import (
 	"Network-go/network/conn"
+	"Network-go/network/logging"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net"
 	"reflect"
+	"sync"
 )
 
-const bufSize = 1024
+const bufSize = 16384 // Large enough for the states of all the elevators (the snapshots grow with the number of orders)
+
+// PortCounters counts the messages handled by the transmitters and receivers of a port, since the start of the process
+type PortCounters struct {
+	Sent           int // Messages broadcast
+	Received       int // Messages decoded and delivered to a channel
+	Dropped        int // Messages that could not be sent or read
+	DecodeFailures int // Messages received that were not valid JSON (or not of the expected type)
+}
+
+var log = logging.New("bcast")
+
+// Counters counts the messages of the transmitters and receivers that share it (e.g. the ones of one elevator),
+// per port. A nil *Counters counts nothing
+type Counters struct {
+	mtx   sync.Mutex
+	ports map[int]*PortCounters
+}
+
+func NewCounters() *Counters {
+	return &Counters{ports: make(map[int]*PortCounters)}
+}
+
+func (counters *Counters) count(port int, update func(*PortCounters)) {
+	if counters == nil {
+		return
+	}
+	counters.mtx.Lock()
+	defer counters.mtx.Unlock()
+	if counters.ports[port] == nil {
+		counters.ports[port] = &PortCounters{}
+	}
+	update(counters.ports[port])
+}
+
+// Ports returns a copy of the counters of every port used so far
+func (counters *Counters) Ports() map[int]PortCounters {
+	copied := make(map[int]PortCounters)
+	if counters == nil {
+		return copied
+	}
+	counters.mtx.Lock()
+	defer counters.mtx.Unlock()
+	for port, c := range counters.ports {
+		copied[port] = *c
+	}
+	return copied
+}
 
 // Encodes received values from `chans` into type-tagged JSON, then broadcasts
-// it on `port`
-func Transmitter(port int, chans ...interface{}) {
+// it on `port`. Returns and closes its socket when `ctx` is cancelled
+func Transmitter(ctx context.Context, port int, chans ...interface{}) {
+	CountingTransmitter(ctx, nil, port, chans...)
+}
+
+// Transmitter that counts its messages in `counters`
+func CountingTransmitter(ctx context.Context, counters *Counters, port int, chans ...interface{}) {
 	checkArgs(chans...)
 	typeNames := make([]string, len(chans))
-	selectCases := make([]reflect.SelectCase, len(typeNames))
+	selectCases := make([]reflect.SelectCase, len(typeNames)+1)
 	for i, ch := range chans {
 		selectCases[i] = reflect.SelectCase{
 			Dir:  reflect.SelectRecv,
@@ -23,30 +78,49 @@ func Transmitter(port int, chans ...interface{}) {
 		}
 		typeNames[i] = reflect.TypeOf(ch).Elem().String()
 	}
+	doneCase := len(chans)
+	selectCases[doneCase] = reflect.SelectCase{
+		Dir:  reflect.SelectRecv,
+		Chan: reflect.ValueOf(ctx.Done()),
+	}
 
 	conn := conn.DialBroadcastUDP(port)
+	defer conn.Close()
 	addr, _ := net.ResolveUDPAddr("udp4", fmt.Sprintf("255.255.255.255:%d", port))
 	for {
 		chosen, value, _ := reflect.Select(selectCases)
+		if chosen == doneCase {
+			return
+		}
 		jsonstr, _ := json.Marshal(value.Interface())
 		ttj, _ := json.Marshal(typeTaggedJSON{
 			TypeId: typeNames[chosen],
 			JSON:   jsonstr,
 		})
-		if len(ttj) > bufSize {
-		    panic(fmt.Sprintf(
-		        "Tried to send a message longer than the buffer size (length: %d, buffer size: %d)\n\t'%s'\n"+
-		        "Either send smaller packets, or go to network/bcast/bcast.go and increase the buffer size",
-		        len(ttj), bufSize, string(ttj)))
+		if len(ttj) > bufSize { // The receivers could not read it
+			log.Errorf("Transmitter(%d): dropped a %s longer than the buffer size (length: %d, buffer size: %d)",
+				port, typeNames[chosen], len(ttj), bufSize)
+			counters.count(port, func(c *PortCounters) { c.Dropped++ })
+			continue
+		}
+		if _, err := conn.WriteTo(ttj, addr); err != nil {
+			log.Warnf("Transmitter(%d): could not send a %s: %v", port, typeNames[chosen], err)
+			counters.count(port, func(c *PortCounters) { c.Dropped++ })
+		} else {
+			counters.count(port, func(c *PortCounters) { c.Sent++ })
 		}
-		conn.WriteTo(ttj, addr)
-    		
 	}
 }
 
 // Matches type-tagged JSON received on `port` to element types of `chans`, then
-// sends the decoded value on the corresponding channel
-func Receiver(port int, chans ...interface{}) {
+// sends the decoded value on the corresponding channel. Returns and closes its
+// socket when `ctx` is cancelled
+func Receiver(ctx context.Context, port int, chans ...interface{}) {
+	CountingReceiver(ctx, nil, port, chans...)
+}
+
+// Receiver that counts its messages in `counters`
+func CountingReceiver(ctx context.Context, counters *Counters, port int, chans ...interface{}) {
 	checkArgs(chans...)
 	chansMap := make(map[string]interface{})
 	for _, ch := range chans {
@@ -55,25 +129,50 @@ func Receiver(port int, chans ...interface{}) {
 
 	var buf [bufSize]byte
 	conn := conn.DialBroadcastUDP(port)
+	go func() {
+		// Unblocks ReadFrom
+		<-ctx.Done()
+		conn.Close()
+	}()
 	for {
 		n, _, e := conn.ReadFrom(buf[0:])
+		if ctx.Err() != nil {
+			return
+		}
 		if e != nil {
-			fmt.Printf("bcast.Receiver(%d, ...):ReadFrom() failed: \"%+v\"\n", port, e)
+			log.Warnf("Receiver(%d): ReadFrom() failed: %v", port, e)
+			counters.count(port, func(c *PortCounters) { c.Dropped++ })
+			continue
 		}
 
 		var ttj typeTaggedJSON
-		json.Unmarshal(buf[0:n], &ttj)
+		if err := json.Unmarshal(buf[0:n], &ttj); err != nil {
+			log.Debugf("Receiver(%d): could not decode a message: %v", port, err)
+			counters.count(port, func(c *PortCounters) { c.DecodeFailures++ })
+			continue
+		}
 		ch, ok := chansMap[ttj.TypeId]
 		if !ok {
 			continue
 		}
 		v := reflect.New(reflect.TypeOf(ch).Elem())
-		json.Unmarshal(ttj.JSON, v.Interface())
-		reflect.Select([]reflect.SelectCase{{
+		if err := json.Unmarshal(ttj.JSON, v.Interface()); err != nil {
+			log.Debugf("Receiver(%d): could not decode a %s: %v", port, ttj.TypeId, err)
+			counters.count(port, func(c *PortCounters) { c.DecodeFailures++ })
+			continue
+		}
+		chosen, _, _ := reflect.Select([]reflect.SelectCase{{
 			Dir:  reflect.SelectSend,
 			Chan: reflect.ValueOf(ch),
 			Send: reflect.Indirect(v),
+		}, {
+			Dir:  reflect.SelectRecv,
+			Chan: reflect.ValueOf(ctx.Done()),
 		}})
+		if chosen == 1 {
+			return
+		}
+		counters.count(port, func(c *PortCounters) { c.Received++ })
 	}
 }
 
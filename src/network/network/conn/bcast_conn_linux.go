@@ -3,7 +3,6 @@
 package conn
 
 import (
-	"fmt"
 	"net"
 	"os"
 	"syscall"
@@ -11,17 +10,17 @@ import (
 
 func DialBroadcastUDP(port int) net.PacketConn {
 	s, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM, syscall.IPPROTO_UDP)
-	if err != nil { fmt.Println("Error: Socket:", err) }
+	if err != nil { log.Errorf("Socket: %v", err) }
 	syscall.SetsockoptInt(s, syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1)
-	if err != nil { fmt.Println("Error: SetSockOpt REUSEADDR:", err) }
+	if err != nil { log.Errorf("SetSockOpt REUSEADDR: %v", err) }
 	syscall.SetsockoptInt(s, syscall.SOL_SOCKET, syscall.SO_BROADCAST, 1)
-	if err != nil { fmt.Println("Error: SetSockOpt BROADCAST:", err) }
+	if err != nil { log.Errorf("SetSockOpt BROADCAST: %v", err) }
 	syscall.Bind(s, &syscall.SockaddrInet4{Port: port})
-	if err != nil { fmt.Println("Error: Bind:", err) }
+	if err != nil { log.Errorf("Bind: %v", err) }
 
 	f := os.NewFile(uintptr(s), "")
 	conn, err := net.FilePacketConn(f)
-	if err != nil { fmt.Println("Error: FilePacketConn:", err) }
+	if err != nil { log.Errorf("FilePacketConn: %v", err) }
 	f.Close()
 
 	return conn
@@ -0,0 +1,5 @@
+package conn
+
+import "Network-go/network/logging"
+
+var log = logging.New("bcast") // The sockets are opened for bcast (and peers)
@@ -0,0 +1,191 @@
+// Package logging is the leveled logger shared by the network packages and the elevator client.
+// Every line carries its level, its component (e.g. bcast, peers, master) and, when known, the id and the role of
+// the elevator. The lines are written as text or as JSON, and the verbosity can be set per component
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Level int
+
+const (
+	Debug Level = iota
+	Info
+	Warn
+	Error
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < Debug || l > Error {
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+	return levelNames[l]
+}
+
+// ParseLevel parses debug, info, warn or error ("" is info)
+func ParseLevel(name string) (Level, error) {
+	if name == "" {
+		return Info, nil
+	}
+	for level, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return Level(level), nil
+		}
+	}
+	return Info, fmt.Errorf("Unknown log level %q (debug, info, warn or error)", name)
+}
+
+// Config is the verbosity and the format of the logs
+type Config struct {
+	Level      string            // The level of every component: debug, info, warn or error ("" is info)
+	Components map[string]string // The level of some components, e.g. {"bcast": "debug", "peers": "warn"}
+	JSON       bool              // One JSON object per line instead of text
+}
+
+// ParseComponents parses the levels of the components given as "bcast=debug,peers=warn"
+func ParseComponents(value string) (map[string]string, error) {
+	components := make(map[string]string)
+	for _, item := range strings.Split(value, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		fields := strings.SplitN(item, "=", 2)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("Expected <component>=<level>, got %q", item)
+		}
+		components[strings.TrimSpace(fields[0])] = strings.TrimSpace(fields[1])
+	}
+	return components, nil
+}
+
+// The configuration in use, shared by the whole process
+var (
+	mtx             sync.Mutex
+	output          io.Writer            = os.Stdout
+	defaultLevel                         = Info
+	componentLevels                      = make(map[string]Level)
+	jsonOutput                           = false
+	processIdentity func() (int, string) // See SetIdentity
+)
+
+// Configure sets the verbosity and the format of every logger
+func Configure(cfg Config) error {
+	level, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return err
+	}
+	levels := make(map[string]Level)
+	for component, name := range cfg.Components {
+		if levels[component], err = ParseLevel(name); err != nil {
+			return fmt.Errorf("%s: %v", component, err)
+		}
+	}
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	defaultLevel, componentLevels, jsonOutput = level, levels, cfg.JSON
+	return nil
+}
+
+// SetOutput sets where the lines are written (os.Stdout by default)
+func SetOutput(w io.Writer) {
+	mtx.Lock()
+	defer mtx.Unlock()
+	output = w
+}
+
+// SetIdentity gives the id and the role of the elevator of the process, for the loggers that do not know them
+// (e.g. the ones of the network packages)
+func SetIdentity(identity func() (id int, role string)) {
+	mtx.Lock()
+	defer mtx.Unlock()
+	processIdentity = identity
+}
+
+// Logger writes the lines of one component
+type Logger struct {
+	component string
+	identity  func() (int, string) // nil: the one of the process
+}
+
+// New returns the logger of a component
+func New(component string) Logger {
+	return Logger{component: component}
+}
+
+// WithIdentity returns the same logger, whose lines carry the id and the role returned by identity
+func (l Logger) WithIdentity(identity func() (id int, role string)) Logger {
+	l.identity = identity
+	return l
+}
+
+// Enabled tells whether the lines of this level are written
+func (l Logger) Enabled(level Level) bool {
+	mtx.Lock()
+	defer mtx.Unlock()
+	minimum, set := componentLevels[l.component]
+	if !set {
+		minimum = defaultLevel
+	}
+	return level >= minimum
+}
+
+func (l Logger) Debugf(format string, args ...interface{}) { l.Logf(Debug, format, args...) }
+func (l Logger) Infof(format string, args ...interface{})  { l.Logf(Info, format, args...) }
+func (l Logger) Warnf(format string, args ...interface{})  { l.Logf(Warn, format, args...) }
+func (l Logger) Errorf(format string, args ...interface{}) { l.Logf(Error, format, args...) }
+
+type jsonLine struct {
+	Time      time.Time `json:"time"`
+	Level     string    `json:"level"`
+	Component string    `json:"component"`
+	Id        *int      `json:"id,omitempty"`
+	Role      string    `json:"role,omitempty"`
+	Message   string    `json:"msg"`
+}
+
+// Logf writes a line, if its level is enabled for the component
+func (l Logger) Logf(level Level, format string, args ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	now := time.Now()
+	message := fmt.Sprintf(format, args...)
+
+	mtx.Lock()
+	identity, asJSON := l.identity, jsonOutput
+	if identity == nil {
+		identity = processIdentity
+	}
+	mtx.Unlock()
+	var id *int
+	role := ""
+	if identity != nil { // Called without the mutex, it may log itself
+		elevatorId, elevatorRole := identity()
+		id, role = &elevatorId, elevatorRole
+	}
+
+	var line []byte
+	if asJSON {
+		line, _ = json.Marshal(jsonLine{Time: now, Level: level.String(), Component: l.component, Id: id, Role: role, Message: message})
+	} else {
+		who := "-"
+		if id != nil {
+			who = fmt.Sprintf("E%d/%s", *id, role)
+		}
+		line = []byte(fmt.Sprintf("%s %-5s %-7s %s %s", now.Format("15:04:05.000"), strings.ToUpper(level.String()), l.component, who, message))
+	}
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	output.Write(append(line, '\n'))
+}
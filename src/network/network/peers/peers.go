@@ -2,6 +2,8 @@ package peers
 
 import (
 	"Network-go/network/conn"
+	"Network-go/network/logging"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net"
@@ -15,8 +17,11 @@ import (
 	This allows us to send both the ID (fixed) and the role (variable) of an elevator
 - Had to change the Receiver function so that it is only detecting peer losses depending on their ID and not
 	on the entire ElevIdentity struct
+- Both functions take a context, they return and close their socket when it is cancelled
 */
 
+var log = logging.New("peers")
+
 type ElevIdentity struct {
 	Id   int    `json:"ID"`
 	Role string `json:"Role"`
@@ -31,8 +36,9 @@ type PeerUpdate struct {
 const interval = 15 * time.Millisecond
 const timeout = 500 * time.Millisecond
 
-func Transmitter(port int, id int, roleChan <-chan string, transmitEnable <-chan bool) {
+func Transmitter(ctx context.Context, port int, id int, roleChan <-chan string, transmitEnable <-chan bool) {
 	conn := conn.DialBroadcastUDP(port)
+	defer conn.Close()
 	addr, _ := net.ResolveUDPAddr("udp4", fmt.Sprintf("255.255.255.255:%d", port))
 
 	enable := true
@@ -46,6 +52,8 @@ func Transmitter(port int, id int, roleChan <-chan string, transmitEnable <-chan
 			currentRole = newRole
 			msg.Role = currentRole
 		case <-time.After(interval):
+		case <-ctx.Done():
+			return
 		}
 		if enable {
 			data, err := json.Marshal(msg)
@@ -57,15 +65,16 @@ func Transmitter(port int, id int, roleChan <-chan string, transmitEnable <-chan
 	}
 }
 
-func Receiver(port int, peerUpdateCh chan<- PeerUpdate) {
+func Receiver(ctx context.Context, port int, peerUpdateCh chan<- PeerUpdate) {
 	var buf [1024]byte
 
 	lastSeen := make(map[int]time.Time)        // Track last seen time by Id
 	idToIdentity := make(map[int]ElevIdentity) // Track latest ElevIdentity by Id
 
 	conn := conn.DialBroadcastUDP(port)
+	defer conn.Close()
 
-	for {
+	for ctx.Err() == nil {
 		updated := false
 		var p PeerUpdate
 
@@ -82,6 +91,7 @@ func Receiver(port int, peerUpdateCh chan<- PeerUpdate) {
 
 				// If this is a new peer (new ID)
 				if !exists {
+					log.Debugf("New peer %d (%s)", receivedID.Id, receivedID.Role)
 					p.New = receivedID
 					updated = true
 				} else {
@@ -100,6 +110,7 @@ func Receiver(port int, peerUpdateCh chan<- PeerUpdate) {
 		for id, t := range lastSeen {
 			if now.Sub(t) > timeout {
 				updated = true
+				log.Debugf("Lost peer %d (%s), not heard for %v", id, idToIdentity[id].Role, now.Sub(t))
 				// Append the last known identity before deletion
 				p.Lost = append(p.Lost, idToIdentity[id])
 				delete(lastSeen, id)
@@ -122,7 +133,10 @@ func Receiver(port int, peerUpdateCh chan<- PeerUpdate) {
 				return p.Lost[i].Id < p.Lost[j].Id
 			})
 
-			peerUpdateCh <- p
+			select {
+			case peerUpdateCh <- p:
+			case <-ctx.Done():
+			}
 		}
 	}
 }